@@ -0,0 +1,107 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+// Package account handles account data of matrix users. Account data is a key value store on the homeserver
+// that is private to the user. Entries are either global or bound to a room and are also delivered via sync.
+// Applications may use it to store their own configuration under custom types.
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"eqrx.net/matrix"
+)
+
+func dataPath(cli matrix.Client, room, eventType string) string {
+	if eventType == "" {
+		panic("event type empty")
+	}
+
+	path := "/_matrix/client/v3/user/" + url.PathEscape(cli.User())
+	if room != "" {
+		path += "/rooms/" + url.PathEscape(room)
+	}
+
+	return path + "/account_data/" + url.PathEscape(eventType)
+}
+
+func get(ctx context.Context, cli matrix.Client, path string, content interface{}) error {
+	var response json.RawMessage
+
+	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return fmt.Errorf("get account data: %w", err)
+	}
+
+	if err := json.Unmarshal(response, content); err != nil {
+		return fmt.Errorf("unmarshal account data: %w", err)
+	}
+
+	return nil
+}
+
+func put(ctx context.Context, cli matrix.Client, path string, content interface{}) error {
+	if content == nil {
+		panic("content empty")
+	}
+
+	var response matrix.Response
+
+	if err := cli.HTTP(ctx, http.MethodPut, path, content, &response); err != nil {
+		return fmt.Errorf("put account data: %w", err)
+	}
+
+	return response.AsError()
+}
+
+// Get the global account data of the given type for the user of the given client and decode it into T.
+// Returns an error matching matrix.IsErrorCode(err, "M_NOT_FOUND") if there is none.
+func Get[T any](ctx context.Context, cli matrix.Client, eventType string) (T, error) {
+	var content T
+
+	err := get(ctx, cli, dataPath(cli, "", eventType), &content)
+
+	return content, err
+}
+
+// Put sets the global account data of the given type for the user of the given client to the given content.
+func Put(ctx context.Context, cli matrix.Client, eventType string, content interface{}) error {
+	return put(ctx, cli, dataPath(cli, "", eventType), content)
+}
+
+// GetRoom gets the account data of the given type bound to the given room ID for the user of the given client and
+// decodes it into T. Returns an error matching matrix.IsErrorCode(err, "M_NOT_FOUND") if there is none.
+func GetRoom[T any](ctx context.Context, cli matrix.Client, room, eventType string) (T, error) {
+	if room == "" {
+		panic("room id empty")
+	}
+
+	var content T
+
+	err := get(ctx, cli, dataPath(cli, room, eventType), &content)
+
+	return content, err
+}
+
+// PutRoom sets the account data of the given type bound to the given room ID for the user of the given client to the
+// given content.
+func PutRoom(ctx context.Context, cli matrix.Client, room, eventType string, content interface{}) error {
+	if room == "" {
+		panic("room id empty")
+	}
+
+	return put(ctx, cli, dataPath(cli, room, eventType), content)
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"eqrx.net/matrix/event"
+)
+
+const (
+	// EventTypeDirect in a event type field indicates that the account data event lists direct chats.
+	EventTypeDirect = "m.direct"
+	// EventTypeIgnoredUserList in a event type field indicates that the account data event lists ignored users.
+	EventTypeIgnoredUserList = "m.ignored_user_list"
+	// EventTypeSecretStorageDefaultKey in a event type field indicates that the account data event names the
+	// default secret storage key.
+	EventTypeSecretStorageDefaultKey = "m.secret_storage.default_key"
+	// EventTypePrefixSecretStorageKey is the prefix of event types of account data events that describe a secret
+	// storage key. The rest of the type is the ID of the key.
+	EventTypePrefixSecretStorageKey = "m.secret_storage.key."
+)
+
+//nolint:gochecknoinits // Account data of sync responses is decoded with event.As, which needs these types.
+func init() {
+	event.Register[DirectContent](EventTypeDirect)
+	event.Register[IgnoredUserListContent](EventTypeIgnoredUserList)
+	event.Register[SecretStorageDefaultKeyContent](EventTypeSecretStorageDefaultKey)
+}
+
+// DirectContent maps user IDs to the IDs of rooms that are direct chats with them.
+type DirectContent map[string][]string
+
+// IgnoredUserListContent represents the content of the ignored users account data event.
+type IgnoredUserListContent struct {
+	IgnoredUsers map[string]struct{} `json:"ignored_users"`
+}
+
+// SecretStorageDefaultKeyContent represents the content of the account data event naming the default secret
+// storage key.
+type SecretStorageDefaultKeyContent struct {
+	Key string `json:"key"`
+}
+
+// SecretStorageKeyContent represents the content of an account data event describing a secret storage key.
+type SecretStorageKeyContent struct {
+	Name       string                   `json:"name,omitempty"`
+	Algorithm  string                   `json:"algorithm"`
+	Passphrase *SecretStoragePassphrase `json:"passphrase,omitempty"`
+	IV         string                   `json:"iv,omitempty"`
+	MAC        string                   `json:"mac,omitempty"`
+}
+
+// SecretStoragePassphrase describes how to derive a secret storage key from a passphrase.
+type SecretStoragePassphrase struct {
+	Algorithm  string `json:"algorithm"`
+	Salt       string `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Bits       int    `json:"bits,omitempty"`
+}
+
+// SecretStorageKeyEventType returns the event type of the account data event describing the secret storage key
+// with the given ID.
+func SecretStorageKeyEventType(id string) string {
+	return EventTypePrefixSecretStorageKey + id
+}
+
+// AsSecretStorageKey converts the given opaque event to the description of a secret storage key. Returns the ID of
+// the key and its description. Returns an error if metadata indicates that the event does not describe a secret
+// storage key or if unmarshalling of the content failed.
+func AsSecretStorageKey(evt event.Opaque) (string, SecretStorageKeyContent, error) {
+	var content SecretStorageKeyContent
+
+	id := strings.TrimPrefix(evt.Type, EventTypePrefixSecretStorageKey)
+	if id == evt.Type || id == "" {
+		return "", content, fmt.Errorf("%w: %s is no secret storage key", event.ErrTypeMismatch, evt.Type)
+	}
+
+	if err := json.Unmarshal(evt.Content, &content); err != nil {
+		return "", content, fmt.Errorf("unmarshal %s content: %w", evt.Type, err)
+	}
+
+	return id, content, nil
+}
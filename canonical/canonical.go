@@ -0,0 +1,244 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+// Package canonical encodes JSON in the canonical form matrix uses for hashing and signing. Canonical JSON has its
+// object keys sorted, contains no insignificant whitespace, is UTF-8 encoded and only allows integers in the range
+// a float64 can represent exactly.
+package canonical
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxInt = 1<<53 - 1
+	minInt = -maxInt
+)
+
+var (
+	// ErrNumber is returned if a value contains a number that is not an integer or not in the allowed range.
+	ErrNumber = errors.New("number not allowed in canonical json")
+	// ErrUTF8 is returned if a value contains a string that is not valid UTF-8.
+	ErrUTF8 = errors.New("invalid utf-8 in canonical json")
+)
+
+// Marshal encodes the given value as canonical JSON. Returns an error wrapping ErrUTF8 if a string of the value is
+// not valid UTF-8, json.Marshal would silently replace the invalid bytes otherwise.
+func Marshal(value interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := checkUTF8(reflect.ValueOf(value)); err != nil {
+		return nil, err
+	}
+
+	return Transform(encoded)
+}
+
+// checkUTF8 ensures that all strings json.Marshal encodes from the given value are valid UTF-8. Byte slices like
+// json.RawMessage are left to Transform.
+func checkUTF8(value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.String:
+		if !utf8.ValidString(value.String()) {
+			return fmt.Errorf("%w: %q", ErrUTF8, value.String())
+		}
+	case reflect.Interface, reflect.Ptr:
+		if !value.IsNil() {
+			return checkUTF8(value.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			if err := checkUTF8(value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			if err := checkUTF8(iter.Key()); err != nil {
+				return err
+			}
+
+			if err := checkUTF8(iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() || strings.Split(field.Tag.Get("json"), ",")[0] == "-" {
+				continue
+			}
+
+			if err := checkUTF8(value.Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Transform converts the given JSON to canonical JSON. Returns an error wrapping ErrUTF8 if the JSON is not valid
+// UTF-8 and one wrapping ErrNumber if it contains numbers that are not integers in the allowed range.
+func Transform(raw []byte) ([]byte, error) {
+	if !utf8.Valid(raw) {
+		return nil, fmt.Errorf("%w: %q", ErrUTF8, raw)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unmarshal: trailing data after json value")
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, value); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(value))
+	case json.Number:
+		return encodeNumber(buf, value)
+	case string:
+		encodeString(buf, value)
+	case []interface{}:
+		buf.WriteByte('[')
+
+		for i, elem := range value {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := encode(buf, elem); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		return encodeObject(buf, value)
+	default:
+		panic(fmt.Sprintf("unexpected json type %T", value))
+	}
+
+	return nil
+}
+
+func encodeObject(buf *bytes.Buffer, object map[string]interface{}) error {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+
+	// Byte wise order of UTF-8 strings equals the order of their code points.
+	sort.Strings(keys)
+
+	buf.WriteByte('{')
+
+	for i, key := range keys {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+
+		encodeString(buf, key)
+		buf.WriteByte(':')
+
+		if err := encode(buf, object[key]); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return nil
+}
+
+func encodeNumber(buf *bytes.Buffer, number json.Number) error {
+	value, err := strconv.ParseInt(number.String(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNumber, number)
+	}
+
+	if value < minInt || value > maxInt {
+		return fmt.Errorf("%w: %s out of range", ErrNumber, number)
+	}
+
+	buf.WriteString(strconv.FormatInt(value, 10))
+
+	return nil
+}
+
+func encodeString(buf *bytes.Buffer, str string) {
+	const hex = "0123456789abcdef"
+
+	buf.WriteByte('"')
+
+	for i := 0; i < len(str); i++ {
+		char := str[i]
+
+		switch {
+		case char == '"' || char == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(char)
+		case char == '\b':
+			buf.WriteString(`\b`)
+		case char == '\f':
+			buf.WriteString(`\f`)
+		case char == '\n':
+			buf.WriteString(`\n`)
+		case char == '\r':
+			buf.WriteString(`\r`)
+		case char == '\t':
+			buf.WriteString(`\t`)
+		case char < 0x20:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[char>>4])
+			buf.WriteByte(hex[char&0xf])
+		default:
+			buf.WriteByte(char)
+		}
+	}
+
+	buf.WriteByte('"')
+}
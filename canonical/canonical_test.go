@@ -0,0 +1,93 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package canonical_test
+
+import (
+	"errors"
+	"testing"
+
+	"eqrx.net/matrix/canonical"
+)
+
+func TestTransform(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]string{
+		`{}`:                       `{}`,
+		`{"one": 1, "two": "Two"}`: `{"one":1,"two":"Two"}`,
+		`{"b": "2", "a": "1"}`:     `{"a":"1","b":"2"}`,
+		`{"auth": {"success": true, "mxid": "@john.doe:example.com", "profile": {"display_name": "John Doe",
+			"three_pids": [{"medium": "email", "address": "john.doe@example.org"},
+			{"medium": "msisdn", "address": "123456789"}]}}}`: `{"auth":{"mxid":"@john.doe:example.com",` +
+			`"profile":{"display_name":"John Doe","three_pids":[{"address":"john.doe@example.org","medium":"email"},` +
+			`{"address":"123456789","medium":"msisdn"}]},"success":true}}`,
+		`{"a": "日本語"}`:     `{"a":"日本語"}`,
+		`{"本": 2, "日": 1}`: `{"日":1,"本":2}`,
+		`{"a": "\u65E5"}`:  `{"a":"日"}`,
+		`{"a": null}`:      `{"a":null}`,
+		`{"a": -0}`:        `{"a":0}`,
+	} {
+		got, err := canonical.Transform([]byte(input))
+		if err != nil {
+			t.Errorf("transform %s: %v", input, err)
+
+			continue
+		}
+
+		if string(got) != want {
+			t.Errorf("transform %s: got %s, want %s", input, got, want)
+		}
+	}
+}
+
+func TestTransformRejects(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]error{
+		`{"a":1.5}`:               canonical.ErrNumber,
+		`{"a":1e2}`:               canonical.ErrNumber,
+		`{"a":9007199254740992}`:  canonical.ErrNumber,
+		`{"a":-9007199254740992}`: canonical.ErrNumber,
+		"{\"a\":\"\xff\"}":        canonical.ErrUTF8,
+		"{\"\xff\":1}":            canonical.ErrUTF8,
+	} {
+		if _, err := canonical.Transform([]byte(input)); !errors.Is(err, want) {
+			t.Errorf("transform %q: got error %v, want %v", input, err, want)
+		}
+	}
+}
+
+func TestMarshalRejectsInvalidUTF8(t *testing.T) {
+	t.Parallel()
+
+	type object struct {
+		Values  map[string][]string `json:"values"`
+		Ignored string              `json:"-"`
+	}
+
+	for _, value := range []interface{}{
+		"\xff",
+		map[string]string{"\xff": "a"},
+		object{Values: map[string][]string{"a": {"b", "\xff"}}},
+		&object{Values: map[string][]string{"a": {"\xff"}}},
+	} {
+		if _, err := canonical.Marshal(value); !errors.Is(err, canonical.ErrUTF8) {
+			t.Errorf("marshal %#v: got error %v, want %v", value, err, canonical.ErrUTF8)
+		}
+	}
+
+	if _, err := canonical.Marshal(object{Ignored: "\xff"}); err != nil {
+		t.Errorf("marshal ignored field: %v", err)
+	}
+}
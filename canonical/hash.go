@@ -0,0 +1,97 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package canonical
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"eqrx.net/matrix/event"
+)
+
+// Without returns the canonical JSON of the given value with the given top level keys removed.
+// The value must encode to a JSON object.
+func Without(value interface{}, keys ...string) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("unmarshal object: %w", err)
+	}
+
+	for _, key := range keys {
+		delete(fields, key)
+	}
+
+	return Marshal(fields)
+}
+
+// ContentHash computes the SHA-256 content hash of the given PDU as used in its hashes field.
+func ContentHash(evt event.Opaque) ([]byte, error) {
+	encoded, err := Without(evt, "unsigned", "signatures", "hashes")
+	if err != nil {
+		return nil, fmt.Errorf("content hash: %w", err)
+	}
+
+	hash := sha256.Sum256(encoded)
+
+	return hash[:], nil
+}
+
+// ReferenceHash computes the SHA-256 reference hash of the given PDU of a room with the given version.
+// Room versions 3 and later derive event IDs from it.
+func ReferenceHash(evt event.Opaque, roomVersion string) ([]byte, error) {
+	redacted, err := event.Redact(evt, roomVersion)
+	if err != nil {
+		return nil, fmt.Errorf("reference hash: %w", err)
+	}
+
+	encoded, err := Without(redacted, "unsigned", "signatures")
+	if err != nil {
+		return nil, fmt.Errorf("reference hash: %w", err)
+	}
+
+	hash := sha256.Sum256(encoded)
+
+	return hash[:], nil
+}
+
+// EventID computes the ID of the given PDU of a room with the given version. Only room versions 3 and later derive
+// event IDs from the event, older versions return an error.
+func EventID(evt event.Opaque, roomVersion string) (string, error) {
+	version, err := event.RoomVersion(roomVersion)
+	if err != nil {
+		return "", err
+	}
+
+	if version < 3 {
+		return "", fmt.Errorf("room version %d does not derive event ids from events", version)
+	}
+
+	hash, err := ReferenceHash(evt, roomVersion)
+	if err != nil {
+		return "", err
+	}
+
+	if version == 3 {
+		return "$" + base64.RawStdEncoding.EncodeToString(hash), nil
+	}
+
+	return "$" + base64.RawURLEncoding.EncodeToString(hash), nil
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+package canonical_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"eqrx.net/matrix/canonical"
+	"eqrx.net/matrix/event"
+)
+
+// signingEvent is the minimal event of the signing events example in the appendix of the matrix specification.
+const signingEvent = `{"room_id":"!x:domain","sender":"@a:domain","origin":"domain","origin_server_ts":1000000,
+	"signatures":{},"hashes":{},"type":"X","content":{},"prev_events":[],"auth_events":[],"depth":3,
+	"unsigned":{"age_ts":1000000}}`
+
+func TestContentHash(t *testing.T) {
+	t.Parallel()
+
+	var evt event.Opaque
+	if err := json.Unmarshal([]byte(signingEvent), &evt); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	hash, err := canonical.ContentHash(evt)
+	if err != nil {
+		t.Fatalf("content hash: %v", err)
+	}
+
+	want := "5jM4wQpv6lnBo7CLIghJuHdW+s2CMBJPUOGOC89ncos"
+	if got := base64.RawStdEncoding.EncodeToString(hash); got != want {
+		t.Errorf("got hash %s, want %s", got, want)
+	}
+}
+
+// referenceEvent returns a PDU with the given depth that carries the content hash of the signing events example.
+func referenceEvent(t *testing.T, depth string) event.Opaque {
+	t.Helper()
+
+	input := `{"room_id":"!x:domain","sender":"@a:domain","origin":"domain","origin_server_ts":1000000,
+		"signatures":{"domain":{"ed25519:1":"x"}},"hashes":{"sha256":"5jM4wQpv6lnBo7CLIghJuHdW+s2CMBJPUOGOC89ncos"},
+		"type":"X","content":{"body":"redacted"},"prev_events":[],"auth_events":[],"depth":` + depth + `,
+		"unsigned":{"age_ts":1000000}}`
+
+	var evt event.Opaque
+	if err := json.Unmarshal([]byte(input), &evt); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	return evt
+}
+
+func TestEventID(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		depth, version, hash, id string
+	}{
+		// Version 3 uses standard base64, later versions the URL safe alphabet.
+		{
+			"1", "3", "Rqq28Xl5TkTYFtoKggVAJyNX98aVoSI+dtQX8NZ3+kc",
+			"$Rqq28Xl5TkTYFtoKggVAJyNX98aVoSI+dtQX8NZ3+kc",
+		},
+		{
+			"1", "4", "Rqq28Xl5TkTYFtoKggVAJyNX98aVoSI+dtQX8NZ3+kc",
+			"$Rqq28Xl5TkTYFtoKggVAJyNX98aVoSI-dtQX8NZ3-kc",
+		},
+		{
+			"2", "10", "Hn4gAkOf402WL41b/lLLapT3J0Nk8j9LoHHgPFsJnVo",
+			"$Hn4gAkOf402WL41b_lLLapT3J0Nk8j9LoHHgPFsJnVo",
+		},
+		// Version 11 does not keep the origin key when redacting.
+		{
+			"3", "11", "70O/oKlXzFbkfu0KE88USi98DjSWrOELrPj+8tisl8I",
+			"$70O_oKlXzFbkfu0KE88USi98DjSWrOELrPj-8tisl8I",
+		},
+	} {
+		evt := referenceEvent(t, test.depth)
+
+		hash, err := canonical.ReferenceHash(evt, test.version)
+		if err != nil {
+			t.Errorf("depth %s, version %s: reference hash: %v", test.depth, test.version, err)
+		} else if got := base64.RawStdEncoding.EncodeToString(hash); got != test.hash {
+			t.Errorf("depth %s, version %s: got hash %s, want %s", test.depth, test.version, got, test.hash)
+		}
+
+		id, err := canonical.EventID(evt, test.version)
+		if err != nil {
+			t.Errorf("depth %s, version %s: event id: %v", test.depth, test.version, err)
+		} else if id != test.id {
+			t.Errorf("depth %s, version %s: got id %s, want %s", test.depth, test.version, id, test.id)
+		}
+	}
+
+	if _, err := canonical.EventID(referenceEvent(t, "1"), "2"); err == nil {
+		t.Error("version 2: expected error")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+// Package device keeps track of devices of matrix users and their keys and exchanges messages with them.
+package device
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"eqrx.net/matrix"
+)
+
+// Keys are the identity keys of a device as published by the device itself.
+type Keys struct {
+	User       string                       `json:"user_id"`
+	Device     string                       `json:"device_id"`
+	Algorithms []string                     `json:"algorithms"`
+	Keys       map[string]string            `json:"keys"`
+	Signatures map[string]map[string]string `json:"signatures"`
+	Unsigned   *UnsignedKeyData             `json:"unsigned,omitempty"`
+}
+
+// UnsignedKeyData is information about a device added by the homeserver.
+type UnsignedKeyData struct {
+	DisplayName string `json:"device_display_name,omitempty"`
+}
+
+type queryRequest struct {
+	DeviceKeys map[string][]string `json:"device_keys"`
+	Timeout    int                 `json:"timeout,omitempty"`
+}
+
+type queryResponse struct {
+	matrix.Response
+	DeviceKeys map[string]map[string]Keys `json:"device_keys"`
+	Failures   map[string]interface{}     `json:"failures"`
+}
+
+// Query the device keys of the given users with the given client. The returned map maps user IDs to device IDs to
+// device keys. Devices whose keys do not state the user and device ID they are listed under are dropped.
+// Also returns the names of remote homeservers that could not be reached. Users of those servers are missing
+// from the returned map.
+func Query(ctx context.Context, cli matrix.Client, users ...string) (map[string]map[string]Keys, []string, error) {
+	request := queryRequest{DeviceKeys: make(map[string][]string, len(users)), Timeout: 10000}
+	for _, user := range users {
+		request.DeviceKeys[user] = []string{}
+	}
+
+	var response queryResponse
+
+	if err := cli.HTTP(ctx, http.MethodPost, "/_matrix/client/v3/keys/query", request, &response); err != nil {
+		return nil, nil, fmt.Errorf("query keys: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return nil, nil, err
+	}
+
+	failures := make([]string, 0, len(response.Failures))
+	for server := range response.Failures {
+		failures = append(failures, server)
+	}
+
+	for user, devices := range response.DeviceKeys {
+		for id, keys := range devices {
+			if keys.User != user || keys.Device != id {
+				delete(devices, id)
+			}
+		}
+	}
+
+	return response.DeviceKeys, failures, nil
+}
+
+// Changes lists the users whose devices changed and the users the client stopped sharing an encrypted room with
+// between the sync tokens from and to.
+type Changes struct {
+	Changed []string `json:"changed"`
+	Left    []string `json:"left"`
+}
+
+type changesResponse struct {
+	matrix.Response
+	Changes
+}
+
+// QueryChanges requests the device list changes between the sync tokens from and to with the given client.
+func QueryChanges(ctx context.Context, cli matrix.Client, from, to string) (Changes, error) {
+	if from == "" || to == "" {
+		panic("parameter empty")
+	}
+
+	path := "/_matrix/client/v3/keys/changes?from=" + url.QueryEscape(from) + "&to=" + url.QueryEscape(to)
+
+	var response changesResponse
+
+	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return Changes{}, fmt.Errorf("query key changes: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return Changes{}, err
+	}
+
+	return response.Changes, nil
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"eqrx.net/matrix"
+	"eqrx.net/matrix/event"
+	matrixsync "eqrx.net/matrix/sync"
+)
+
+// AllDevices may be used as device ID in Messages to address all devices of a user.
+const AllDevices = "*"
+
+// Messages to send to devices. Maps user IDs to device IDs (or AllDevices) to the content to send. Create them with
+// NewMessages, Add panics on a nil Messages like on any nil map.
+type Messages map[string]map[string]interface{}
+
+// NewMessages creates empty messages to fill with Add.
+func NewMessages() Messages {
+	return Messages{}
+}
+
+// Add the given content for the given user and device to the messages and returns them. Panics if the messages are
+// nil.
+func (m Messages) Add(user, device string, content interface{}) Messages {
+	if user == "" || device == "" || content == nil {
+		panic("parameter empty")
+	}
+
+	if m == nil {
+		panic("messages nil, create them with NewMessages")
+	}
+
+	if m[user] == nil {
+		m[user] = map[string]interface{}{}
+	}
+
+	m[user][device] = content
+
+	return m
+}
+
+type sendRequest struct {
+	Messages Messages `json:"messages"`
+}
+
+// Send the given messages of the given event type to the addressed devices via the given client.
+func Send(ctx context.Context, cli matrix.Client, eventType string, messages Messages) error {
+	if eventType == "" || len(messages) == 0 {
+		panic("parameter empty")
+	}
+
+	path := "/_matrix/client/v3/sendToDevice/" + url.PathEscape(eventType) + "/" + cli.NextTXID()
+
+	var response matrix.Response
+
+	if err := cli.HTTP(ctx, http.MethodPut, path, sendRequest{messages}, &response); err != nil {
+		return fmt.Errorf("send to device: %w", err)
+	}
+
+	return response.AsError()
+}
+
+// Event is an event sent directly to the device of the client.
+type Event struct {
+	Sender  string              `json:"sender"`
+	Type    string              `json:"type"`
+	Content event.OpaqueContent `json:"content"`
+}
+
+// AsEvent converts the given opaque event to a to-device event.
+func AsEvent(evt event.Opaque) Event {
+	return Event{evt.Sender, evt.Type, evt.Content}
+}
+
+// Received returns the to-device events of the given sync response. If event types are given, only events of
+// these types are returned.
+func Received(response matrixsync.Response, eventTypes ...string) []Event {
+	events := make([]Event, 0, len(response.ToDevice.Events))
+
+	for _, evt := range response.ToDevice.Events {
+		if len(eventTypes) != 0 && !contains(eventTypes, evt.Type) {
+			continue
+		}
+
+		events = append(events, AsEvent(evt))
+	}
+
+	return events
+}
+
+// Unmarshal the content of the event into the given pointer.
+func (e Event) Unmarshal(content interface{}) error {
+	if err := json.Unmarshal(e.Content, content); err != nil {
+		return fmt.Errorf("unmarshal %s content from %s: %w", e.Type, e.Sender, err)
+	}
+
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+
+	return false
+}
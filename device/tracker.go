@@ -0,0 +1,285 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package device
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"eqrx.net/matrix"
+	matrixsync "eqrx.net/matrix/sync"
+)
+
+// Tracker keeps a local cache of the devices of other users and their keys. Users have to be tracked explicitly,
+// normally all users the client shares an encrypted room with. Device list changes reported by sync responses
+// mark tracked users as outdated and Run refreshes them in the background.
+type Tracker struct {
+	mtx      sync.Mutex
+	users    map[string]*trackedUser
+	otkCount map[string]int
+	wake     chan struct{}
+}
+
+type trackedUser struct {
+	// generation is increased each time the user is marked outdated. A refresh only clears the outdated flag
+	// if no change was reported while it was in flight.
+	generation uint64
+	outdated   bool
+	devices    map[string]Keys
+}
+
+// NewTracker creates a new tracker that does not track any users.
+func NewTracker() *Tracker {
+	return &Tracker{users: map[string]*trackedUser{}, wake: make(chan struct{}, 1)}
+}
+
+func (t *Tracker) notify() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (t *Tracker) markOutdated(user string) {
+	tracked, ok := t.users[user]
+	if !ok {
+		return
+	}
+
+	tracked.generation++
+	tracked.outdated = true
+}
+
+// Track starts tracking the devices of the given users. Users not tracked yet are refreshed by the next run.
+func (t *Tracker) Track(users ...string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for _, user := range users {
+		if _, ok := t.users[user]; ok {
+			continue
+		}
+
+		t.users[user] = &trackedUser{generation: 1, outdated: true}
+	}
+
+	t.notify()
+}
+
+// Untrack stops tracking the devices of the given users and drops their cached devices.
+func (t *Tracker) Untrack(users ...string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for _, user := range users {
+		delete(t.users, user)
+	}
+}
+
+// Update the tracker with the device list changes of the given sync response. Tracked users listed as changed
+// are marked as outdated and users listed as left are not tracked anymore. Also stores the one time key counts
+// of the device of the client.
+func (t *Tracker) Update(response matrixsync.Response) {
+	t.ApplyChanges(Changes(response.DeviceLists))
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if response.DeviceOneTimeKeysCount != nil {
+		t.otkCount = response.DeviceOneTimeKeysCount
+	}
+}
+
+// ApplyChanges marks tracked users listed as changed as outdated and stops tracking users listed as left.
+func (t *Tracker) ApplyChanges(changes Changes) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for _, user := range changes.Changed {
+		t.markOutdated(user)
+	}
+
+	for _, user := range changes.Left {
+		delete(t.users, user)
+	}
+
+	if len(changes.Changed) != 0 {
+		t.notify()
+	}
+}
+
+// CatchUp requests the device list changes between the sync tokens from and to with the given client and applies
+// them. Use this after the sync loop was interrupted and the client resumes with an older token.
+func (t *Tracker) CatchUp(ctx context.Context, cli matrix.Client, from, to string) error {
+	changes, err := QueryChanges(ctx, cli, from, to)
+	if err != nil {
+		return err
+	}
+
+	t.ApplyChanges(changes)
+
+	return nil
+}
+
+// Devices returns the cached devices of the given user mapped by device ID. Returns false if the user is not
+// tracked or its devices are outdated. The cache may still be returned in the later case.
+func (t *Tracker) Devices(user string) (map[string]Keys, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	tracked, ok := t.users[user]
+	if !ok {
+		return nil, false
+	}
+
+	devices := make(map[string]Keys, len(tracked.devices))
+	for id, keys := range tracked.devices {
+		devices[id] = keys
+	}
+
+	return devices, !tracked.outdated
+}
+
+// OneTimeKeyCounts returns the number of unclaimed one time keys of the client device by algorithm as reported
+// by the last sync response passed to Update.
+func (t *Tracker) OneTimeKeyCounts() map[string]int {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	counts := make(map[string]int, len(t.otkCount))
+	for algorithm, count := range t.otkCount {
+		counts[algorithm] = count
+	}
+
+	return counts
+}
+
+// Refresh queries the keys of all outdated users with the given client and updates the cache. Users whose
+// homeserver could not be reached stay outdated.
+func (t *Tracker) Refresh(ctx context.Context, cli matrix.Client) error {
+	_, err := t.refresh(ctx, cli)
+
+	return err
+}
+
+// refresh does the work of Refresh and additionally returns if users are still outdated afterwards.
+func (t *Tracker) refresh(ctx context.Context, cli matrix.Client) (bool, error) {
+	t.mtx.Lock()
+
+	generations := map[string]uint64{}
+
+	for user, tracked := range t.users {
+		if tracked.outdated {
+			generations[user] = tracked.generation
+		}
+	}
+
+	t.mtx.Unlock()
+
+	if len(generations) == 0 {
+		return false, nil
+	}
+
+	users := make([]string, 0, len(generations))
+	for user := range generations {
+		users = append(users, user)
+	}
+
+	keys, _, err := Query(ctx, cli, users...)
+	if err != nil {
+		return true, err
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for user, devices := range keys {
+		tracked, ok := t.users[user]
+		if !ok {
+			continue
+		}
+
+		tracked.devices = devices
+
+		if tracked.generation == generations[user] {
+			tracked.outdated = false
+		}
+	}
+
+	for _, tracked := range t.users {
+		if tracked.outdated {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Delays between refreshes while users stay outdated because refreshes fail or homeservers are not reachable.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+// Run refreshes outdated users with the given client each time users get tracked or marked as outdated until the
+// context is done. Run is meant to be called in its own goroutine next to the sync loop that calls Update.
+// Failed refreshes are passed to report, which may be nil. While users stay outdated, refreshes are retried with
+// an exponentially increasing delay. Returns the error of the context.
+func (t *Tracker) Run(ctx context.Context, cli matrix.Client, report func(error)) error {
+	delay := minRetryDelay
+
+	for {
+		pending, err := t.refresh(ctx, cli)
+		if err != nil && report != nil && ctx.Err() == nil {
+			report(err)
+		}
+
+		if !pending {
+			delay = minRetryDelay
+		}
+
+		if err := t.wait(ctx, delay, pending); err != nil {
+			return err
+		}
+
+		if pending {
+			if delay *= 2; delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+		}
+	}
+}
+
+// wait blocks until the tracker is woken up or the context is done. If retry is set, it also returns after the
+// given delay.
+func (t *Tracker) wait(ctx context.Context, delay time.Duration, retry bool) error {
+	var timeout <-chan time.Time
+
+	if retry {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.wake:
+	case <-timeout:
+	}
+
+	return nil
+}
@@ -11,39 +11,63 @@
 // You should have received a copy of the GNU Affero General Public License along with this program.
 // If not, see <https://www.gnu.org/licenses/>.
 
-// Package event defines the basic event type for interacting with matrix servers.
+// Package event defines the basic event type for interacting with matrix servers and a registry that maps event
+// types to typed content.
 package event
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Opaque is an event with Metadata and OpaqueContent as content. To get a more concrete type out of this check the
 // value of the type field in the metadata and unmarshal the event into concrete types.
+//
+// Top level fields not covered by Metadata are kept in Extra so an Opaque can be marshalled again without losing
+// anything, like the hashes and signatures of federation PDUs. Known fields that were present with a zero value are
+// marshalled again as well.
 type Opaque struct {
 	Metadata
-	Content OpaqueContent `json:"content"`
+	Content OpaqueContent              `json:"content"`
+	Extra   map[string]json.RawMessage `json:"-"`
+
+	present presentFields
 }
 
 // Metadata of an event. Depending of the event type fields may be set or not.
 //
 // Reason for this is that there are multiple groups of event types in matrix.
 // One could have created types for each of them but I do not see the benefit.
+//
+// StateKey is nil for events that are not state events. Note that an empty state key is valid for state events.
+// Received is not part of the event but set by the sync package to the local time the event was received.
 type Metadata struct {
 	Type      string        `json:"type"`
 	ID        string        `json:"event_id"`
 	Sender    string        `json:"sender"`
 	Room      string        `json:"room_id"`
-	StateKey  string        `json:"state_key"`
-	Timestamp int           `json:"origin_server_ts"`
+	StateKey  *string       `json:"state_key"`
+	Timestamp Timestamp     `json:"origin_server_ts"`
 	Unsigned  *UnsignedData `json:"unsigned"`
+	Received  time.Time     `json:"-"`
 }
 
+// IsState returns true if the metadata belongs to a state event.
+func (m Metadata) IsState() bool { return m.StateKey != nil }
+
+// StateKey returns a pointer to the given state key for use in Metadata.
+func StateKey(key string) *string { return &key }
+
 // UnsignedData is the portion of Metadata that is not set by sender but
-// servers on the way and it thus unsigned.
+// servers on the way and it thus unsigned. Fields not covered are kept in Extra.
 type UnsignedData struct {
-	Age             int           `json:"age"`
-	TXID            string        `json:"transaction_id"`
-	PreviousContent OpaqueContent `json:"prev_content"`
-	RedactedBecause *Opaque       `json:"redacted_because"`
+	Age             Age                        `json:"age"`
+	TXID            string                     `json:"transaction_id"`
+	PreviousContent OpaqueContent              `json:"prev_content"`
+	RedactedBecause *Opaque                    `json:"redacted_because"`
+	Extra           map[string]json.RawMessage `json:"-"`
+
+	present presentFields
 }
 
 // OpaqueContent is the content field of an event that should not be interpreted (yet).
@@ -56,9 +80,21 @@ type OpaqueContent []byte
 
 // UnmarshalJSON tells the json unmarshaller to leave the content field as is.
 func (o *OpaqueContent) UnmarshalJSON(b []byte) error {
-	*o = b
+	*o = append((*o)[:0], b...)
 
 	return nil
 }
 
-var _ json.Unmarshaler = &OpaqueContent{}
+// MarshalJSON tells the json marshaller to put the content field in as is. Empty content is marshalled as null.
+func (o OpaqueContent) MarshalJSON() ([]byte, error) {
+	if len(o) == 0 {
+		return []byte("null"), nil
+	}
+
+	return o, nil
+}
+
+var (
+	_ json.Unmarshaler = &OpaqueContent{}
+	_ json.Marshaler   = OpaqueContent{}
+)
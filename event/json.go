@@ -0,0 +1,178 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The plain types have the same fields as their counterparts but none of their methods. They are used to let the
+// json package do the work for known fields without recursing into the custom (un)marshallers.
+type (
+	plainOpaque   Opaque
+	plainUnsigned UnsignedData
+)
+
+// fieldSet collects the top level fields of an object while marshalling.
+type fieldSet map[string]json.RawMessage
+
+func newFieldSet(extra map[string]json.RawMessage) fieldSet {
+	fields := make(fieldSet, len(extra))
+	for key, value := range extra {
+		fields[key] = value
+	}
+
+	return fields
+}
+
+func (f fieldSet) set(key string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", key, err)
+	}
+
+	f[key] = encoded
+
+	return nil
+}
+
+// setIf sets the field if cond is true. Errors of previous calls are passed through.
+func (f fieldSet) setIf(err error, cond bool, key string, value interface{}) error {
+	if err != nil || !cond {
+		return err
+	}
+
+	return f.set(key, value)
+}
+
+// presentFields records which known fields were present in an unmarshalled object so they are marshalled again even
+// if their value is the zero value.
+type presentFields map[string]struct{}
+
+func (p presentFields) has(key string) bool {
+	_, ok := p[key]
+
+	return ok
+}
+
+// splitFields returns all fields of the given object that are not in known and the known fields that are present.
+func splitFields(b []byte, known ...string) (map[string]json.RawMessage, presentFields, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal fields: %w", err)
+	}
+
+	present := presentFields{}
+
+	for _, key := range known {
+		if _, ok := fields[key]; ok {
+			present[key] = struct{}{}
+			delete(fields, key)
+		}
+	}
+
+	if len(fields) == 0 {
+		fields = nil
+	}
+
+	return fields, present, nil
+}
+
+// UnmarshalJSON unmarshals the event and keeps unknown top level fields in Extra.
+func (o *Opaque) UnmarshalJSON(b []byte) error {
+	var plain plainOpaque
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return fmt.Errorf("unmarshal event: %w", err)
+	}
+
+	extra, present, err := splitFields(
+		b, "type", "event_id", "sender", "room_id", "state_key", "origin_server_ts", "unsigned", "content",
+	)
+	if err != nil {
+		return err
+	}
+
+	plain.Extra = extra
+	plain.present = present
+	*o = Opaque(plain)
+
+	return nil
+}
+
+// MarshalJSON marshals the event including the fields in Extra. Fields of Metadata are left out if they are empty,
+// StateKey only if it is nil. Fields that were present when the event was unmarshalled are always kept.
+func (o Opaque) MarshalJSON() ([]byte, error) {
+	fields := newFieldSet(o.Extra)
+	has := o.present.has
+
+	err := fields.setIf(nil, o.Type != "" || has("type"), "type", o.Type)
+	err = fields.setIf(err, o.ID != "" || has("event_id"), "event_id", o.ID)
+	err = fields.setIf(err, o.Sender != "" || has("sender"), "sender", o.Sender)
+	err = fields.setIf(err, o.Room != "" || has("room_id"), "room_id", o.Room)
+	err = fields.setIf(err, o.StateKey != nil || has("state_key"), "state_key", o.StateKey)
+	err = fields.setIf(err, o.Timestamp != 0 || has("origin_server_ts"), "origin_server_ts", o.Timestamp)
+	err = fields.setIf(err, o.Unsigned != nil || has("unsigned"), "unsigned", o.Unsigned)
+	err = fields.setIf(err, o.Content != nil || has("content"), "content", o.Content)
+
+	if err != nil {
+		return nil, fmt.Errorf("marshal event: %w", err)
+	}
+
+	return json.Marshal(map[string]json.RawMessage(fields))
+}
+
+// UnmarshalJSON unmarshals the unsigned data and keeps unknown fields in Extra.
+func (u *UnsignedData) UnmarshalJSON(b []byte) error {
+	var plain plainUnsigned
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return fmt.Errorf("unmarshal unsigned data: %w", err)
+	}
+
+	extra, present, err := splitFields(b, "age", "transaction_id", "prev_content", "redacted_because")
+	if err != nil {
+		return err
+	}
+
+	plain.Extra = extra
+	plain.present = present
+	*u = UnsignedData(plain)
+
+	return nil
+}
+
+// MarshalJSON marshals the unsigned data including the fields in Extra. Empty fields are left out unless they were
+// present when the unsigned data was unmarshalled.
+func (u UnsignedData) MarshalJSON() ([]byte, error) {
+	fields := newFieldSet(u.Extra)
+	has := u.present.has
+
+	err := fields.setIf(nil, u.Age != 0 || has("age"), "age", u.Age)
+	err = fields.setIf(err, u.TXID != "" || has("transaction_id"), "transaction_id", u.TXID)
+	err = fields.setIf(err, u.PreviousContent != nil || has("prev_content"), "prev_content", u.PreviousContent)
+	err = fields.setIf(err, u.RedactedBecause != nil || has("redacted_because"), "redacted_because", u.RedactedBecause)
+
+	if err != nil {
+		return nil, fmt.Errorf("marshal unsigned data: %w", err)
+	}
+
+	return json.Marshal(map[string]json.RawMessage(fields))
+}
+
+var (
+	_ json.Unmarshaler = &Opaque{}
+	_ json.Marshaler   = Opaque{}
+	_ json.Unmarshaler = &UnsignedData{}
+	_ json.Marshaler   = UnsignedData{}
+)
@@ -0,0 +1,157 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package event_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"eqrx.net/matrix/canonical"
+	"eqrx.net/matrix/event"
+	"eqrx.net/matrix/sync"
+)
+
+// roundTripCorpus contains example events of the matrix specification and edge cases with zero values.
+//
+//nolint:gochecknoglobals,lll // Test corpus.
+var roundTripCorpus = map[string]string{
+	"message": `{"content":{"body":"This is an example text message","format":"org.matrix.custom.html",
+		"formatted_body":"<b>This is an example text message</b>","msgtype":"m.text"},
+		"event_id":"$143273582443PhrSn:example.org","origin_server_ts":1432735824653,
+		"room_id":"!jEsUZKDJdhlrceRyVU:example.org","sender":"@example:example.org","type":"m.room.message",
+		"unsigned":{"age":1234,"membership":"join"}}`,
+	"member": `{"content":{"avatar_url":"mxc://example.org/SEsfnsuifSDFSSEF","displayname":"Alice Margatroid",
+		"membership":"join","reason":"Looking for support"},"event_id":"$143273582443PhrSn:example.org",
+		"origin_server_ts":1432735824653,"room_id":"!jEsUZKDJdhlrceRyVU:example.org","sender":"@example:example.org",
+		"state_key":"@alice:example.org","type":"m.room.member","unsigned":{"age":1234,"membership":"join"}}`,
+	"state with previous content": `{"content":{"name":"The room name"},"event_id":"$143273582443PhrSn:example.org",
+		"origin_server_ts":1432735824653,"room_id":"!jEsUZKDJdhlrceRyVU:example.org","sender":"@example:example.org",
+		"state_key":"","type":"m.room.name","unsigned":{"age":1234,"prev_content":{"name":"Old name"},
+		"replaces_state":"$somewhere:example.org"}}`,
+	"redacted": `{"content":{},"event_id":"$143273582443PhrSn:example.org","origin_server_ts":1432735824653,
+		"room_id":"!jEsUZKDJdhlrceRyVU:example.org","sender":"@example:example.org","type":"m.room.message",
+		"unsigned":{"redacted_because":{"content":{"reason":"Spamming"},"event_id":"$fukweghifu23:localhost",
+		"origin_server_ts":1432735824653,"redacts":"$143273582443PhrSn:example.org",
+		"room_id":"!jEsUZKDJdhlrceRyVU:example.org","sender":"@example:example.org","type":"m.room.redaction",
+		"unsigned":{"age":1234}}}}`,
+	"transaction": `{"content":{"body":"hi","msgtype":"m.text"},"event_id":"$a:example.org","origin_server_ts":1,
+		"room_id":"!r:example.org","sender":"@example:example.org","type":"m.room.message",
+		"unsigned":{"transaction_id":"m1234"}}`,
+	"to-device": `{"content":{"algorithm":"m.megolm.v1.aes-sha2","room_id":"!Cuyf34gef24t:localhost",
+		"session_id":"X3lUlvLELLYxeTx4yOVu6UDpasGEVO0Jbu+QFnm0cKQ","session_key":"AgAAAADxKHa9uFxcXzwYoNueL5"},
+		"sender":"@alice:example.com","type":"m.room_key"}`,
+	"pdu": `{"auth_events":[],"content":{},"depth":3,"hashes":{"sha256":"5jM4wQpv6lnBo7CLIghJuHdW+s2CMBJPUOGOC89ncos"},
+		"origin":"domain","origin_server_ts":1000000,"prev_events":[],"room_id":"!x:domain","sender":"@a:domain",
+		"signatures":{"domain":{"ed25519:1":"KxwGjPSDEtvnFgU00fwFz+l6d2pJM6XBIaMEn81SXPTRl16AqLAYqfIReFGZlHi5KLjAWbOoMszkwsQma+lYAg"}},
+		"type":"X","unsigned":{"age_ts":1000000}}`,
+	"zero values":     `{"type":"x","content":{},"origin_server_ts":0,"sender":"","unsigned":{"age":0}}`,
+	"null values":     `{"type":"x","content":null,"state_key":null,"unsigned":null}`,
+	"empty state key": `{"type":"x","content":{},"state_key":"","unsigned":{"transaction_id":"","prev_content":{}}}`,
+}
+
+// syncResponse is the example sync response of the matrix specification without floats, which canonical JSON
+// does not allow, and with to-device messages and device list changes added.
+const syncResponse = `{"next_batch":"s72595_4483_1934",
+	"account_data":{"events":[{"content":{"custom_config_key":"custom_config_value"},"type":"org.example.custom.config"}]},
+	"presence":{"events":[{"content":{"avatar_url":"mxc://localhost/wefuiwegh8742w","currently_active":false,
+		"last_active_ago":2478593,"presence":"online","status_msg":"Making cupcakes"},"sender":"@example:localhost",
+		"type":"m.presence"}]},
+	"to_device":{"events":[{"content":{"body":"hi"},"sender":"@alice:example.com","type":"org.example.message"}]},
+	"device_lists":{"changed":["@alice:example.com"],"left":["@bob:example.com"]},
+	"device_one_time_keys_count":{"signed_curve25519":20},
+	"rooms":{
+		"invite":{"!696r7674:example.com":{"invite_state":{"events":[
+			{"content":{"name":"My Room Name"},"sender":"@alice:example.com","state_key":"","type":"m.room.name"},
+			{"content":{"membership":"invite"},"sender":"@alice:example.com","state_key":"@bob:example.com",
+				"type":"m.room.member"}]}}},
+		"join":{"!726s6s6q:example.com":{
+			"account_data":{"events":[{"content":{"tags":{"u.work":{}}},"type":"m.tag"}]},
+			"ephemeral":{"events":[{"content":{"user_ids":["@alice:matrix.org","@bob:example.com"]},"type":"m.typing"}]},
+			"state":{"events":[{"content":{"avatar_url":"mxc://example.org/SEsfnsuifSDFSSEF","displayname":"Alice Margatroid",
+				"membership":"join"},"event_id":"$143273582443PhrSn:example.org","origin_server_ts":1432735824653,
+				"sender":"@example:example.org","state_key":"@alice:example.org","type":"m.room.member",
+				"unsigned":{"age":1234}}]},
+			"summary":{"m.heroes":["@alice:example.com","@bob:example.com"],"m.invited_member_count":0,
+				"m.joined_member_count":2},
+			"timeline":{"events":[{"content":{"body":"This is an example text message","msgtype":"m.text"},
+				"event_id":"$143273582443PhrSn:example.org","origin_server_ts":1432735824653,
+				"sender":"@example:example.org","type":"m.room.message","unsigned":{"age":1234}}],
+				"limited":true,"prev_batch":"t34-23535_0_0"},
+			"unread_notifications":{"highlight_count":1,"notification_count":5},
+			"unread_thread_notifications":{"$threadroot":{"highlight_count":3,"notification_count":6}}}},
+		"knock":{"!223asd456:example.com":{"knock_state":{"events":[
+			{"content":{"name":"My Room Name"},"sender":"@alice:example.com","state_key":"","type":"m.room.name"}]}}},
+		"leave":{"!5345234234:example.com":{"account_data":{"events":[]},
+			"state":{"events":[{"content":{"membership":"leave"},"event_id":"$leave:example.com","origin_server_ts":1,
+				"sender":"@example:example.org","state_key":"@example:example.org","type":"m.room.member"}]},
+			"timeline":{"events":[],"limited":false}}}}}`
+
+func TestSyncResponseRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	want, err := canonical.Transform([]byte(syncResponse))
+	if err != nil {
+		t.Fatalf("canonicalize input: %v", err)
+	}
+
+	var response sync.Response
+	if err := json.Unmarshal([]byte(syncResponse), &response); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	got, err := canonical.Marshal(response)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if string(got) != string(want) {
+		t.Errorf("round trip changed response\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestOpaqueRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for name, input := range roundTripCorpus {
+		input := input
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			want, err := canonical.Transform([]byte(input))
+			if err != nil {
+				t.Fatalf("canonicalize input: %v", err)
+			}
+
+			var evt event.Opaque
+			if err := json.Unmarshal([]byte(input), &evt); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			encoded, err := json.Marshal(evt)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+
+			got, err := canonical.Transform(encoded)
+			if err != nil {
+				t.Fatalf("canonicalize output: %v", err)
+			}
+
+			if string(got) != string(want) {
+				t.Errorf("round trip changed event\n got: %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrUnknownRoomVersion is returned for room versions whose rules are not known.
+var ErrUnknownRoomVersion = errors.New("unknown room version")
+
+const maxRoomVersion = 11
+
+// RoomVersion parses the given room version and returns it as number. Returns an error wrapping ErrUnknownRoomVersion
+// if it is not a stable room version this package knows the rules of.
+func RoomVersion(version string) (int, error) {
+	number, err := strconv.Atoi(version)
+	if err != nil || number < 1 || number > maxRoomVersion {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownRoomVersion, version)
+	}
+
+	return number, nil
+}
+
+// keptTopLevelKeys returns the top level keys of an event that survive redaction in the given room version.
+func keptTopLevelKeys(version int) []string {
+	keys := []string{
+		"event_id", "type", "room_id", "sender", "state_key", "content", "hashes", "signatures", "depth",
+		"prev_events", "auth_events", "origin_server_ts",
+	}
+
+	if version < 11 {
+		keys = append(keys, "origin", "membership", "prev_state")
+	}
+
+	return keys
+}
+
+// keptContentKeys returns the content keys of an event of the given type that survive redaction in the given room
+// version. Returns nil and true if all keys are kept.
+func keptContentKeys(eventType string, version int) ([]string, bool) {
+	switch eventType {
+	case "m.room.member":
+		keys := []string{"membership"}
+		if version >= 9 {
+			keys = append(keys, "join_authorised_via_users_server")
+		}
+
+		return keys, false
+	case "m.room.create":
+		if version >= 11 {
+			return nil, true
+		}
+
+		return []string{"creator"}, false
+	case "m.room.join_rules":
+		if version >= 8 {
+			return []string{"join_rule", "allow"}, false
+		}
+
+		return []string{"join_rule"}, false
+	case "m.room.power_levels":
+		keys := []string{"ban", "events", "events_default", "kick", "redact", "state_default", "users", "users_default"}
+		if version >= 11 {
+			keys = append(keys, "invite")
+		}
+
+		return keys, false
+	case "m.room.aliases":
+		if version <= 5 {
+			return []string{"aliases"}, false
+		}
+	case "m.room.history_visibility":
+		return []string{"history_visibility"}, false
+	case "m.room.redaction":
+		if version >= 11 {
+			return []string{"redacts"}, false
+		}
+	}
+
+	return nil, false
+}
+
+func keepKeys(fields map[string]json.RawMessage, keys []string) map[string]json.RawMessage {
+	kept := make(map[string]json.RawMessage, len(keys))
+
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			kept[key] = value
+		}
+	}
+
+	return kept
+}
+
+// redactContent returns the redacted form of the given content of an event of the given type.
+func redactContent(eventType string, version int, content OpaqueContent) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+
+	if len(content) != 0 {
+		if err := json.Unmarshal(content, &fields); err != nil {
+			return nil, fmt.Errorf("unmarshal content: %w", err)
+		}
+	}
+
+	keys, all := keptContentKeys(eventType, version)
+	if all {
+		return fields, nil
+	}
+
+	kept := keepKeys(fields, keys)
+
+	// Since version 11 the signed part of third party invites survives so the membership can still be authorised.
+	if invite, ok := fields["third_party_invite"]; ok && eventType == "m.room.member" && version >= 11 {
+		var inviteFields map[string]json.RawMessage
+		if err := json.Unmarshal(invite, &inviteFields); err == nil {
+			if signed, ok := inviteFields["signed"]; ok {
+				encoded, err := json.Marshal(map[string]json.RawMessage{"signed": signed})
+				if err != nil {
+					return nil, fmt.Errorf("marshal third party invite: %w", err)
+				}
+
+				kept["third_party_invite"] = encoded
+			}
+		}
+	}
+
+	return kept, nil
+}
+
+// Redact returns the redacted form of the given event according to the rules of the given room version. All keys
+// that are not essential for the room to function are removed, including the unsigned data.
+func Redact(evt Opaque, roomVersion string) (Opaque, error) {
+	version, err := RoomVersion(roomVersion)
+	if err != nil {
+		return Opaque{}, err
+	}
+
+	encoded, err := json.Marshal(evt)
+	if err != nil {
+		return Opaque{}, fmt.Errorf("redact: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return Opaque{}, fmt.Errorf("redact: unmarshal event: %w", err)
+	}
+
+	kept := keepKeys(fields, keptTopLevelKeys(version))
+
+	content, err := redactContent(evt.Type, version, evt.Content)
+	if err != nil {
+		return Opaque{}, fmt.Errorf("redact: %w", err)
+	}
+
+	if kept["content"], err = json.Marshal(content); err != nil {
+		return Opaque{}, fmt.Errorf("redact: marshal content: %w", err)
+	}
+
+	if encoded, err = json.Marshal(kept); err != nil {
+		return Opaque{}, fmt.Errorf("redact: marshal event: %w", err)
+	}
+
+	var redacted Opaque
+	if err := json.Unmarshal(encoded, &redacted); err != nil {
+		return Opaque{}, fmt.Errorf("redact: unmarshal redacted event: %w", err)
+	}
+
+	redacted.Received = evt.Received
+
+	return redacted, nil
+}
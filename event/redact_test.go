@@ -0,0 +1,151 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+package event_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"eqrx.net/matrix/canonical"
+	"eqrx.net/matrix/event"
+)
+
+// redact redacts the given event JSON for the given room version and returns the result as canonical JSON.
+func redact(t *testing.T, input, roomVersion string) string {
+	t.Helper()
+
+	var evt event.Opaque
+	if err := json.Unmarshal([]byte(input), &evt); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	redacted, err := event.Redact(evt, roomVersion)
+	if err != nil {
+		t.Fatalf("redact: %v", err)
+	}
+
+	encoded, err := canonical.Marshal(redacted)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	return string(encoded)
+}
+
+func canonicalize(t *testing.T, input string) string {
+	t.Helper()
+
+	encoded, err := canonical.Transform([]byte(input))
+	if err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+
+	return string(encoded)
+}
+
+func TestRedactContent(t *testing.T) {
+	t.Parallel()
+
+	const (
+		member = `{"membership":"join","displayname":"A","join_authorised_via_users_server":"@b:d",` +
+			`"third_party_invite":{"display_name":"a","signed":{"mxid":"@a:d","token":"t"}}}`
+		create      = `{"creator":"@a:d","room_version":"10","m.federate":true}`
+		joinRules   = `{"join_rule":"restricted","allow":[{"type":"m.room_membership","room_id":"!s:d"}],"x":1}`
+		powerLevels = `{"ban":50,"events":{},"events_default":0,"invite":0,"kick":50,"notifications":{"room":50},` +
+			`"redact":50,"state_default":50,"users":{},"users_default":0}`
+		aliases             = `{"aliases":["#a:d"]}`
+		redaction           = `{"redacts":"$e:d","reason":"spam"}`
+		visibility          = `{"history_visibility":"shared","x":1}`
+		powerLevelsRedacted = `{"ban":50,"events":{},"events_default":0,"kick":50,"redact":50,"state_default":50,` +
+			`"users":{},"users_default":0}`
+	)
+
+	for _, test := range []struct {
+		name, eventType, version, content, want string
+	}{
+		{"member v8", "m.room.member", "8", member, `{"membership":"join"}`},
+		{"member v9", "m.room.member", "9", member, `{"join_authorised_via_users_server":"@b:d","membership":"join"}`},
+		{
+			"member v11", "m.room.member", "11", member,
+			`{"join_authorised_via_users_server":"@b:d","membership":"join",` +
+				`"third_party_invite":{"signed":{"mxid":"@a:d","token":"t"}}}`,
+		},
+		{"create v10", "m.room.create", "10", create, `{"creator":"@a:d"}`},
+		{"create v11", "m.room.create", "11", create, create},
+		{"join rules v7", "m.room.join_rules", "7", joinRules, `{"join_rule":"restricted"}`},
+		{
+			"join rules v8", "m.room.join_rules", "8", joinRules,
+			`{"allow":[{"room_id":"!s:d","type":"m.room_membership"}],"join_rule":"restricted"}`,
+		},
+		{"power levels v10", "m.room.power_levels", "10", powerLevels, powerLevelsRedacted},
+		{
+			"power levels v11", "m.room.power_levels", "11", powerLevels,
+			`{"ban":50,"events":{},"events_default":0,"invite":0,"kick":50,"redact":50,"state_default":50,` +
+				`"users":{},"users_default":0}`,
+		},
+		{"aliases v5", "m.room.aliases", "5", aliases, aliases},
+		{"aliases v6", "m.room.aliases", "6", aliases, `{}`},
+		{"redaction v10", "m.room.redaction", "10", redaction, `{}`},
+		{"redaction v11", "m.room.redaction", "11", redaction, `{"redacts":"$e:d"}`},
+		{"history visibility v1", "m.room.history_visibility", "1", visibility, `{"history_visibility":"shared"}`},
+		{"message", "m.room.message", "11", `{"body":"hi","msgtype":"m.text"}`, `{}`},
+	} {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			input := `{"type":"` + test.eventType + `","room_id":"!r:d","sender":"@a:d","origin_server_ts":1,` +
+				`"state_key":"","content":` + test.content + `}`
+			want := canonicalize(t, `{"type":"`+test.eventType+`","room_id":"!r:d","sender":"@a:d",`+
+				`"origin_server_ts":1,"state_key":"","content":`+test.want+`}`)
+
+			if got := redact(t, input, test.version); got != want {
+				t.Errorf("got  %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRedactTopLevel(t *testing.T) {
+	t.Parallel()
+
+	const input = `{"type":"m.room.message","event_id":"$e:d","room_id":"!r:d","sender":"@a:d",` +
+		`"origin_server_ts":1,"content":{"body":"hi"},"hashes":{"sha256":"x"},"signatures":{"d":{}},"depth":3,` +
+		`"prev_events":[],"auth_events":[],"origin":"d","membership":"join","prev_state":[],"unsigned":{"age":1},` +
+		`"other":true}`
+
+	const kept = `"type":"m.room.message","event_id":"$e:d","room_id":"!r:d","sender":"@a:d",` +
+		`"origin_server_ts":1,"content":{},"hashes":{"sha256":"x"},"signatures":{"d":{}},"depth":3,` +
+		`"prev_events":[],"auth_events":[]`
+
+	for version, want := range map[string]string{
+		"1":  `{` + kept + `,"origin":"d","membership":"join","prev_state":[]}`,
+		"10": `{` + kept + `,"origin":"d","membership":"join","prev_state":[]}`,
+		"11": `{` + kept + `}`,
+	} {
+		if got, want := redact(t, input, version), canonicalize(t, want); got != want {
+			t.Errorf("version %s:\ngot  %s\nwant %s", version, got, want)
+		}
+	}
+}
+
+func TestRedactUnknownVersion(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []string{"", "0", "12", "org.example.custom"} {
+		if _, err := event.Redact(event.Opaque{}, version); err == nil {
+			t.Errorf("version %q: expected error", version)
+		}
+	}
+}
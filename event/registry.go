@@ -0,0 +1,141 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrUnknownType is returned when decoding an event whose type is not registered.
+	ErrUnknownType = errors.New("unknown event type")
+	// ErrTypeMismatch is returned when decoding the content of an event into a type not registered for it.
+	ErrTypeMismatch = errors.New("event type mismatch")
+)
+
+// Registry maps event types to the Go types their content is decoded into.
+type Registry struct {
+	mtx   sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{types: map[string]reflect.Type{}}
+}
+
+// Register the type of the given content value for the given event type. Panics if another type is already
+// registered for the event type.
+func (r *Registry) Register(eventType string, content interface{}) {
+	if content == nil {
+		panic("content empty")
+	}
+
+	r.register(eventType, reflect.TypeOf(content))
+}
+
+func (r *Registry) register(eventType string, contentType reflect.Type) {
+	if eventType == "" {
+		panic("event type empty")
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if registered, ok := r.types[eventType]; ok && registered != contentType {
+		panic(fmt.Sprintf("event type %s already registered as %v", eventType, registered))
+	}
+
+	r.types[eventType] = contentType
+}
+
+// Lookup returns the content type registered for the given event type.
+func (r *Registry) Lookup(eventType string) (reflect.Type, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	contentType, ok := r.types[eventType]
+
+	return contentType, ok
+}
+
+// Parse decodes the content of the given event into a value of the type registered for its event type.
+func (r *Registry) Parse(evt Opaque) (interface{}, error) {
+	contentType, ok := r.Lookup(evt.Type)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, evt.Type)
+	}
+
+	content := reflect.New(contentType)
+	if err := json.Unmarshal(evt.Content, content.Interface()); err != nil {
+		return nil, fmt.Errorf("unmarshal %s content: %w", evt.Type, err)
+	}
+
+	return content.Elem().Interface(), nil
+}
+
+//nolint:gochecknoglobals // Event types are global by nature, same as in encoding/gob.
+var defaultRegistry = NewRegistry()
+
+// Register the content type T for the given event type in the default registry. Panics if another type is already
+// registered for the event type.
+func Register[T any](eventType string) {
+	defaultRegistry.register(eventType, reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Lookup returns the content type registered for the given event type in the default registry.
+func Lookup(eventType string) (reflect.Type, bool) {
+	return defaultRegistry.Lookup(eventType)
+}
+
+// Parse decodes the content of the given event into a value of the type registered for its event type in
+// the default registry.
+func Parse(evt Opaque) (interface{}, error) {
+	return defaultRegistry.Parse(evt)
+}
+
+// CheckType returns an error wrapping ErrUnknownType or ErrTypeMismatch if T is not the content type registered for
+// the given event type in the default registry.
+func CheckType[T any](eventType string) error {
+	contentType, ok := Lookup(eventType)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownType, eventType)
+	}
+
+	if wanted := reflect.TypeOf((*T)(nil)).Elem(); contentType != wanted {
+		return fmt.Errorf("%w: %s is registered as %v, not %v", ErrTypeMismatch, eventType, contentType, wanted)
+	}
+
+	return nil
+}
+
+// As decodes the content of the given event into T. Returns an error if T is not the type registered for the type of
+// the event in the default registry or if the content could not be decoded.
+func As[T any](evt Opaque) (T, error) {
+	var content T
+
+	if err := CheckType[T](evt.Type); err != nil {
+		return content, err
+	}
+
+	if err := json.Unmarshal(evt.Content, &content); err != nil {
+		return content, fmt.Errorf("unmarshal %s content: %w", evt.Type, err)
+	}
+
+	return content, nil
+}
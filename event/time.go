@@ -0,0 +1,52 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package event
+
+import "time"
+
+// Timestamp is a point in time as milliseconds since the unix epoch.
+type Timestamp int64
+
+// TimestampOf returns the timestamp of the given time.
+func TimestampOf(t time.Time) Timestamp { return Timestamp(t.UnixMilli()) }
+
+// Time returns the timestamp as time.
+func (t Timestamp) Time() time.Time { return time.UnixMilli(int64(t)) }
+
+// Age is a duration in milliseconds.
+type Age int64
+
+// Duration returns the age as duration.
+func (a Age) Duration() time.Duration { return time.Duration(a) * time.Millisecond }
+
+// Latency returns how long it took from sending the event until the client received it. The age given by the server
+// is preferred since it does not depend on the clock of the sending server. Returns zero if the event has not been
+// received via sync and carries no age.
+func (m Metadata) Latency() time.Duration {
+	if m.Unsigned != nil && m.Unsigned.Age != 0 {
+		return m.Unsigned.Age.Duration()
+	}
+
+	if m.Received.IsZero() || m.Timestamp == 0 {
+		return 0
+	}
+
+	return m.Received.Sub(m.Timestamp.Time())
+}
+
+// Stale returns true if the event was received more than maxLatency after it was sent, for example because the
+// client was not syncing for a while.
+func (m Metadata) Stale(maxLatency time.Duration) bool {
+	return m.Latency() > maxLatency
+}
@@ -0,0 +1,303 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Builder assembles a Filter step by step. Each method returns the builder so calls may be chained.
+// Build validates the result.
+type Builder struct {
+	filter Filter
+}
+
+// NewBuilder creates a new Builder starting with an empty filter.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Rooms restricts the filter to the given room IDs.
+func (b *Builder) Rooms(ids ...string) *Builder {
+	b.filter.Room.Rooms = append(b.filter.Room.Rooms, ids...)
+
+	return b
+}
+
+// NotRooms excludes the given room IDs.
+func (b *Builder) NotRooms(ids ...string) *Builder {
+	b.filter.Room.NotRooms = append(b.filter.Room.NotRooms, ids...)
+
+	return b
+}
+
+// IncludeLeave includes rooms the user has left.
+func (b *Builder) IncludeLeave() *Builder {
+	b.filter.Room.IncludeLeave = true
+
+	return b
+}
+
+// Timeline applies the given modifications to the timeline filter.
+func (b *Builder) Timeline(mods ...RoomEventMod) *Builder {
+	applyRoomEventMods(&b.filter.Room.Timeline, mods)
+
+	return b
+}
+
+// State applies the given modifications to the room state filter.
+func (b *Builder) State(mods ...RoomEventMod) *Builder {
+	applyRoomEventMods(&b.filter.Room.State, mods)
+
+	return b
+}
+
+// Ephemeral applies the given modifications to the ephemeral room event filter.
+func (b *Builder) Ephemeral(mods ...RoomEventMod) *Builder {
+	applyRoomEventMods(&b.filter.Room.Ephemeral, mods)
+
+	return b
+}
+
+// RoomAccountData applies the given modifications to the room account data filter.
+func (b *Builder) RoomAccountData(mods ...RoomEventMod) *Builder {
+	applyRoomEventMods(&b.filter.Room.AccountData, mods)
+
+	return b
+}
+
+// AccountData applies the given modifications to the global account data filter.
+func (b *Builder) AccountData(mods ...EventMod) *Builder {
+	applyEventMods(&b.filter.AccountData, mods)
+
+	return b
+}
+
+// Presence applies the given modifications to the presence filter.
+func (b *Builder) Presence(mods ...EventMod) *Builder {
+	applyEventMods(&b.filter.Presence, mods)
+
+	return b
+}
+
+// Fields restricts the returned events to the given fields.
+func (b *Builder) Fields(fields ...string) *Builder {
+	b.filter.Fields = append(b.filter.Fields, fields...)
+
+	return b
+}
+
+// Format sets the event format. Either "client" or "federation".
+func (b *Builder) Format(format string) *Builder {
+	b.filter.Format = format
+
+	return b
+}
+
+// Build validates the assembled filter and returns it.
+func (b *Builder) Build() (Filter, error) {
+	if err := b.filter.Validate(); err != nil {
+		return Filter{}, err
+	}
+
+	return b.filter, nil
+}
+
+// EventMod modifies an Event filter.
+type EventMod func(*Event)
+
+// RoomEventMod modifies a RoomEvent filter.
+type RoomEventMod func(*RoomEvent)
+
+func applyEventMods(evt *Event, mods []EventMod) {
+	for _, mod := range mods {
+		mod(evt)
+	}
+}
+
+func applyRoomEventMods(evt *RoomEvent, mods []RoomEventMod) {
+	for _, mod := range mods {
+		mod(evt)
+	}
+}
+
+// Limit sets the maximum number of events returned.
+func Limit(limit int) RoomEventMod { return func(e *RoomEvent) { e.Limit = limit } }
+
+// Types restricts the events to the given types. A "*" may be used as wildcard.
+func Types(types ...string) RoomEventMod {
+	return func(e *RoomEvent) { e.Types = append(e.Types, types...) }
+}
+
+// NotTypes excludes events of the given types. A "*" may be used as wildcard.
+func NotTypes(types ...string) RoomEventMod {
+	return func(e *RoomEvent) { e.NotTypes = append(e.NotTypes, types...) }
+}
+
+// Senders restricts the events to the given sender user IDs.
+func Senders(senders ...string) RoomEventMod {
+	return func(e *RoomEvent) { e.Senders = append(e.Senders, senders...) }
+}
+
+// NotSenders excludes events of the given sender user IDs.
+func NotSenders(senders ...string) RoomEventMod {
+	return func(e *RoomEvent) { e.NotSenders = append(e.NotSenders, senders...) }
+}
+
+// EventRooms restricts the events to the given room IDs.
+func EventRooms(ids ...string) RoomEventMod {
+	return func(e *RoomEvent) { e.Rooms = append(e.Rooms, ids...) }
+}
+
+// EventNotRooms excludes events of the given room IDs.
+func EventNotRooms(ids ...string) RoomEventMod {
+	return func(e *RoomEvent) { e.NotRooms = append(e.NotRooms, ids...) }
+}
+
+// ContainsURL restricts the events to those with (true) or without (false) an url field in their content.
+func ContainsURL(contains bool) RoomEventMod {
+	return func(e *RoomEvent) { e.ContainsURL = &contains }
+}
+
+// LazyLoadMembers only includes membership events of senders of returned events.
+func LazyLoadMembers() RoomEventMod {
+	return func(e *RoomEvent) { e.LazyLoadMembers = true }
+}
+
+// IncludeRedundantMembers sends membership events again even if the server already sent them to the client.
+func IncludeRedundantMembers() RoomEventMod {
+	return func(e *RoomEvent) { e.IncludeRedundantMembers = true }
+}
+
+// UnreadThreadNotifications requests notification counts of threads separately from the counts of the room.
+func UnreadThreadNotifications() RoomEventMod {
+	return func(e *RoomEvent) { e.UnreadThreadNotifications = true }
+}
+
+// EventLimit sets the maximum number of events returned.
+func EventLimit(limit int) EventMod { return func(e *Event) { e.Limit = limit } }
+
+// EventTypes restricts the events to the given types. A "*" may be used as wildcard.
+func EventTypes(types ...string) EventMod {
+	return func(e *Event) { e.Types = append(e.Types, types...) }
+}
+
+// EventNotTypes excludes events of the given types. A "*" may be used as wildcard.
+func EventNotTypes(types ...string) EventMod {
+	return func(e *Event) { e.NotTypes = append(e.NotTypes, types...) }
+}
+
+// EventSenders restricts the events to the given sender user IDs.
+func EventSenders(senders ...string) EventMod {
+	return func(e *Event) { e.Senders = append(e.Senders, senders...) }
+}
+
+// EventNotSenders excludes events of the given sender user IDs.
+func EventNotSenders(senders ...string) EventMod {
+	return func(e *Event) { e.NotSenders = append(e.NotSenders, senders...) }
+}
+
+// ErrInvalid is wrapped by all errors returned by Validate.
+var ErrInvalid = errors.New("invalid filter")
+
+// Validate checks that the filter conforms to the specification. Limits must not be negative, types may only
+// use "*" as wildcard and rooms and senders must be valid identifiers without wildcards.
+func (f Filter) Validate() error {
+	if f.Format != "" && f.Format != "client" && f.Format != "federation" {
+		return fmt.Errorf("%w: unknown event format %q", ErrInvalid, f.Format)
+	}
+
+	if err := f.AccountData.validate(); err != nil {
+		return fmt.Errorf("account data: %w", err)
+	}
+
+	if err := f.Presence.validate(); err != nil {
+		return fmt.Errorf("presence: %w", err)
+	}
+
+	return f.Room.validate()
+}
+
+func (r Room) validate() error {
+	if err := validateIDs("room", '!', r.Rooms, r.NotRooms); err != nil {
+		return err
+	}
+
+	for name, evt := range map[string]RoomEvent{
+		"room account data": r.AccountData,
+		"ephemeral":         r.Ephemeral,
+		"state":             r.State,
+		"timeline":          r.Timeline,
+	} {
+		if err := evt.validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (e Event) validate() error {
+	if e.Limit < 0 {
+		return fmt.Errorf("%w: negative limit %d", ErrInvalid, e.Limit)
+	}
+
+	if err := validateTypes(e.Types, e.NotTypes); err != nil {
+		return err
+	}
+
+	return validateIDs("sender", '@', e.Senders, e.NotSenders)
+}
+
+func (e RoomEvent) validate() error {
+	if err := (Event{e.Limit, e.NotSenders, e.Senders, e.NotTypes, e.Types}).validate(); err != nil {
+		return err
+	}
+
+	return validateIDs("room", '!', e.Rooms, e.NotRooms)
+}
+
+func validateTypes(lists ...[]string) error {
+	for _, list := range lists {
+		for _, eventType := range list {
+			if eventType == "" {
+				return fmt.Errorf("%w: empty event type", ErrInvalid)
+			}
+
+			if strings.ContainsAny(eventType, "?[]") {
+				return fmt.Errorf("%w: event type %q contains a wildcard other than *", ErrInvalid, eventType)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateIDs(kind string, sigil byte, lists ...[]string) error {
+	for _, list := range lists {
+		for _, id := range list {
+			if len(id) < 2 || id[0] != sigil {
+				return fmt.Errorf("%w: invalid %s ID %q", ErrInvalid, kind, id)
+			}
+
+			if strings.Contains(id, "*") {
+				return fmt.Errorf("%w: %s ID %q contains a wildcard", ErrInvalid, kind, id)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package filter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eqrx.net/matrix"
+)
+
+// Store persists the IDs of registered filters under a key derived from the filter content.
+type Store interface {
+	// Load returns the filter ID stored under key or an empty string if there is none.
+	Load(key string) (string, error)
+	// Save stores the filter ID under key.
+	Save(key, id string) error
+}
+
+// DirStore is a Store that keeps each filter ID in a file of the directory it names.
+type DirStore string
+
+// Load implements Store.
+func (d DirStore) Load(key string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(string(d), key))
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "", nil
+	case err != nil:
+		return "", fmt.Errorf("load filter id: %w", err)
+	default:
+		return strings.TrimSpace(string(content)), nil
+	}
+}
+
+// Save implements Store.
+func (d DirStore) Save(key, id string) error {
+	if err := os.WriteFile(filepath.Join(string(d), key), []byte(id), 0o600); err != nil {
+		return fmt.Errorf("save filter id: %w", err)
+	}
+
+	return nil
+}
+
+// canonical returns the filter as JSON with sorted keys so equal filters always result in the same bytes.
+func (f Filter) canonical() ([]byte, error) {
+	encoded, err := json.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("marshal filter: %w", err)
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return nil, fmt.Errorf("unmarshal filter: %w", err)
+	}
+
+	encoded, err = json.Marshal(generic)
+	if err != nil {
+		return nil, fmt.Errorf("marshal filter: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// RegisterCached returns the ID of an already registered filter with the same content as this filter if the given
+// store knows it and the server still has it. Otherwise the filter is registered with the given client and its ID
+// saved to the store. This avoids registering a new filter each time a client starts.
+func (f Filter) RegisterCached(ctx context.Context, cli matrix.Client, store Store) (string, error) {
+	canonical, err := f.canonical()
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(append([]byte(cli.User()+"\n"), canonical...))
+	key := hex.EncodeToString(hash[:])
+
+	id, err := store.Load(key)
+	if err != nil {
+		return "", err
+	}
+
+	if id != "" {
+		matches, err := f.registeredAs(ctx, cli, id, canonical)
+		if err != nil {
+			return "", err
+		}
+
+		if matches {
+			return id, nil
+		}
+	}
+
+	id, err = f.Register(ctx, cli)
+	if err != nil {
+		return "", err
+	}
+
+	if err := store.Save(key, id); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// registeredAs checks if the server has a filter with the given ID and the given canonical content.
+func (f Filter) registeredAs(ctx context.Context, cli matrix.Client, id string, canonical []byte) (bool, error) {
+	registered, err := Get(ctx, cli, id)
+	if matrix.IsErrorCode(err, "M_NOT_FOUND") {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	registeredCanonical, err := registered.canonical()
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(canonical, registeredCanonical), nil
+}
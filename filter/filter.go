@@ -16,7 +16,9 @@ package filter
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/url"
 
 	"eqrx.net/matrix"
 )
@@ -52,30 +54,31 @@ type Room struct {
 
 // RoomEvent allows filering room events.
 type RoomEvent struct {
-	ContainsURL             *bool    `json:"contains_url,omitempty"`
-	IncludeRedundantMembers bool     `json:"include_redundant_members,omitempty"`
-	LazyLoadMembers         bool     `json:"lazy_load_members,omitempty"`
-	Limit                   int      `json:"limit,omitempty"`
-	NotSenders              []string `json:"not_senders,omitempty"`
-	Senders                 []string `json:"senders,omitempty"`
-	NotTypes                []string `json:"not_types,omitempty"`
-	Types                   []string `json:"types,omitempty"`
-	NotRooms                []string `json:"not_rooms,omitempty"`
-	Rooms                   []string `json:"rooms,omitempty"`
+	ContainsURL               *bool    `json:"contains_url,omitempty"`
+	IncludeRedundantMembers   bool     `json:"include_redundant_members,omitempty"`
+	LazyLoadMembers           bool     `json:"lazy_load_members,omitempty"`
+	UnreadThreadNotifications bool     `json:"unread_thread_notifications,omitempty"`
+	Limit                     int      `json:"limit,omitempty"`
+	NotSenders                []string `json:"not_senders,omitempty"`
+	Senders                   []string `json:"senders,omitempty"`
+	NotTypes                  []string `json:"not_types,omitempty"`
+	Types                     []string `json:"types,omitempty"`
+	NotRooms                  []string `json:"not_rooms,omitempty"`
+	Rooms                     []string `json:"rooms,omitempty"`
 }
 
-type response struct {
+type registerResponse struct {
 	matrix.Response
 	Filter string `json:"filter_id"`
 }
 
 // Register the filter with the given matrix client and return its ID.
 func (f Filter) Register(ctx context.Context, cli matrix.Client) (string, error) {
-	var response response
+	var response registerResponse
 
-	path := "/_matrix/client/v3/user/" + cli.User() + "/filter"
+	path := "/_matrix/client/v3/user/" + url.PathEscape(cli.User()) + "/filter"
 	if err := cli.HTTP(ctx, http.MethodPost, path, f, &response); err != nil {
-		return "", err
+		return "", fmt.Errorf("register filter: %w", err)
 	}
 
 	if err := response.AsError(); err != nil {
@@ -84,3 +87,28 @@ func (f Filter) Register(ctx context.Context, cli matrix.Client) (string, error)
 
 	return response.Filter, nil
 }
+
+type getResponse struct {
+	matrix.Response
+	Filter
+}
+
+// Get the filter with the given ID that was registered by the user of the given client.
+func Get(ctx context.Context, cli matrix.Client, id string) (Filter, error) {
+	if id == "" {
+		panic("filter id empty")
+	}
+
+	var response getResponse
+
+	path := "/_matrix/client/v3/user/" + url.PathEscape(cli.User()) + "/filter/" + url.PathEscape(id)
+	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return Filter{}, fmt.Errorf("get filter: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return Filter{}, err
+	}
+
+	return response.Filter, nil
+}
@@ -0,0 +1,144 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+
+	"eqrx.net/matrix/event"
+)
+
+// Matches returns true if the given event passes the filter. Limit is not taken into account.
+func (e Event) Matches(evt event.Opaque) bool {
+	if containsString(e.NotSenders, evt.Sender) {
+		return false
+	}
+
+	if len(e.Senders) != 0 && !containsString(e.Senders, evt.Sender) {
+		return false
+	}
+
+	if matchesAnyType(e.NotTypes, evt.Type) {
+		return false
+	}
+
+	return len(e.Types) == 0 || matchesAnyType(e.Types, evt.Type)
+}
+
+// Matches returns true if the given event of the given room ID passes the filter. If the room ID is empty, the one
+// of the event is used. Limit and member loading options are not taken into account.
+func (e RoomEvent) Matches(evt event.Opaque, roomID string) bool {
+	if roomID == "" {
+		roomID = evt.Room
+	}
+
+	if !(Event{e.Limit, e.NotSenders, e.Senders, e.NotTypes, e.Types}).Matches(evt) {
+		return false
+	}
+
+	if !allowsRoom(e.Rooms, e.NotRooms, roomID) {
+		return false
+	}
+
+	return e.ContainsURL == nil || *e.ContainsURL == containsURL(evt.Content)
+}
+
+// AllowsRoom returns true if events of the given room ID pass the room restrictions of the filter.
+func (r Room) AllowsRoom(roomID string) bool {
+	return allowsRoom(r.Rooms, r.NotRooms, roomID)
+}
+
+// MatchesTimeline returns true if the given timeline event of the given room ID passes the filter.
+func (f Filter) MatchesTimeline(evt event.Opaque, roomID string) bool {
+	if roomID == "" {
+		roomID = evt.Room
+	}
+
+	return f.Room.AllowsRoom(roomID) && f.Room.Timeline.Matches(evt, roomID)
+}
+
+// MatchesState returns true if the given state event of the given room ID passes the filter.
+func (f Filter) MatchesState(evt event.Opaque, roomID string) bool {
+	if roomID == "" {
+		roomID = evt.Room
+	}
+
+	return f.Room.AllowsRoom(roomID) && f.Room.State.Matches(evt, roomID)
+}
+
+func allowsRoom(rooms, notRooms []string, roomID string) bool {
+	if containsString(notRooms, roomID) {
+		return false
+	}
+
+	return len(rooms) == 0 || containsString(rooms, roomID)
+}
+
+func containsURL(content event.OpaqueContent) bool {
+	var fields struct {
+		URL json.RawMessage `json:"url"`
+	}
+
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return false
+	}
+
+	return len(fields.URL) != 0 && string(fields.URL) != "null"
+}
+
+func containsString(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesAnyType(patterns []string, eventType string) bool {
+	for _, pattern := range patterns {
+		if matchWildcard(pattern, eventType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchWildcard matches value against pattern where "*" in pattern matches any sequence of characters.
+func matchWildcard(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+
+	value = value[len(parts[0]):]
+
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(value, part)
+		if idx < 0 {
+			return false
+		}
+
+		value = value[idx+len(part):]
+	}
+
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
@@ -59,7 +59,12 @@ func httpResponse(httpResp *http.Response, response interface{}) (err error) {
 	}()
 
 	if httpResp.StatusCode != http.StatusOK {
-		return fmt.Errorf("status code: %v", httpResp.Status)
+		var errResp Response
+
+		// The body is only a hint, the status code is the error either way.
+		_ = json.NewDecoder(httpResp.Body).Decode(&errResp)
+
+		return &Error{httpResp.StatusCode, errResp.ErrCode, errResp.ErrMsg}
 	}
 
 	return json.NewDecoder(httpResp.Body).Decode(response)
@@ -0,0 +1,208 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package push
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"eqrx.net/matrix"
+	"eqrx.net/matrix/event"
+)
+
+// FromAccountData returns the push rules contained in the given global account data events, for example those of
+// a sync response. Returns false if the events do not contain push rules.
+func FromAccountData(events []event.Opaque) (Ruleset, bool, error) {
+	for _, evt := range events {
+		if evt.Type != EventTypePushRules {
+			continue
+		}
+
+		content, err := event.As[RulesContent](evt)
+		if err != nil {
+			return Ruleset{}, false, err
+		}
+
+		return content.Global, true, nil
+	}
+
+	return Ruleset{}, false, nil
+}
+
+func rulePath(kind, id string, elems ...string) string {
+	if kind == "" || id == "" {
+		panic("parameter empty")
+	}
+
+	path := "/_matrix/client/v3/pushrules/global/" + url.PathEscape(kind) + "/" + url.PathEscape(id)
+	for _, elem := range elems {
+		path += "/" + elem
+	}
+
+	return path
+}
+
+// errorResponse is implemented by all response types that embed matrix.Response.
+type errorResponse interface {
+	AsError() error
+}
+
+// exchange does the given request and returns the error of the response. The response may be nil if the caller is
+// not interested in the response body.
+func exchange(ctx context.Context, cli matrix.Client, method, path string, request interface{},
+	response errorResponse,
+) error {
+	if response == nil {
+		response = &matrix.Response{}
+	}
+
+	if err := cli.HTTP(ctx, method, path, request, response); err != nil {
+		return fmt.Errorf("push rules: %w", err)
+	}
+
+	return response.AsError()
+}
+
+// List all push rules of the user of the given client.
+func List(ctx context.Context, cli matrix.Client) (Ruleset, error) {
+	var response struct {
+		matrix.Response
+		RulesContent
+	}
+
+	err := exchange(ctx, cli, http.MethodGet, "/_matrix/client/v3/pushrules/", nil, &response)
+
+	return response.Global, err
+}
+
+// Get the push rule of the given kind and ID of the user of the given client.
+func Get(ctx context.Context, cli matrix.Client, kind, id string) (Rule, error) {
+	var response struct {
+		matrix.Response
+		Rule
+	}
+
+	err := exchange(ctx, cli, http.MethodGet, rulePath(kind, id), nil, &response)
+
+	return response.Rule, err
+}
+
+type putRequest struct {
+	Actions    []Action    `json:"actions"`
+	Conditions []Condition `json:"conditions,omitempty"`
+	Pattern    string      `json:"pattern,omitempty"`
+}
+
+// Put creates or replaces the given push rule of the given kind for the user of the given client. If before or after
+// is set to the ID of another rule of the same kind, the rule is placed before or after that rule.
+// Only the ID, actions, conditions and pattern of the rule are used.
+func Put(ctx context.Context, cli matrix.Client, kind string, rule Rule, before, after string) error {
+	path := rulePath(kind, rule.ID)
+
+	query := url.Values{}
+	if before != "" {
+		query.Set("before", before)
+	}
+
+	if after != "" {
+		query.Set("after", after)
+	}
+
+	if len(query) != 0 {
+		path += "?" + query.Encode()
+	}
+
+	actions := rule.Actions
+	if actions == nil {
+		actions = []Action{}
+	}
+
+	return exchange(ctx, cli, http.MethodPut, path, putRequest{actions, rule.Conditions, rule.Pattern}, nil)
+}
+
+// Delete the push rule of the given kind and ID of the user of the given client.
+func Delete(ctx context.Context, cli matrix.Client, kind, id string) error {
+	return exchange(ctx, cli, http.MethodDelete, rulePath(kind, id), nil, nil)
+}
+
+type enabledBody struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Enabled returns if the push rule of the given kind and ID of the user of the given client is enabled.
+func Enabled(ctx context.Context, cli matrix.Client, kind, id string) (bool, error) {
+	var response struct {
+		matrix.Response
+		enabledBody
+	}
+
+	err := exchange(ctx, cli, http.MethodGet, rulePath(kind, id, "enabled"), nil, &response)
+
+	return response.Enabled, err
+}
+
+// SetEnabled enables or disables the push rule of the given kind and ID of the user of the given client.
+func SetEnabled(ctx context.Context, cli matrix.Client, kind, id string, enabled bool) error {
+	return exchange(ctx, cli, http.MethodPut, rulePath(kind, id, "enabled"), enabledBody{enabled}, nil)
+}
+
+type actionsBody struct {
+	Actions []Action `json:"actions"`
+}
+
+// Actions returns the actions of the push rule of the given kind and ID of the user of the given client.
+func Actions(ctx context.Context, cli matrix.Client, kind, id string) ([]Action, error) {
+	var response struct {
+		matrix.Response
+		actionsBody
+	}
+
+	err := exchange(ctx, cli, http.MethodGet, rulePath(kind, id, "actions"), nil, &response)
+
+	return response.Actions, err
+}
+
+// SetActions sets the actions of the push rule of the given kind and ID of the user of the given client.
+func SetActions(ctx context.Context, cli matrix.Client, kind, id string, actions []Action) error {
+	if actions == nil {
+		actions = []Action{}
+	}
+
+	return exchange(ctx, cli, http.MethodPut, rulePath(kind, id, "actions"), actionsBody{actions}, nil)
+}
+
+// MuteRoom adds a room rule without actions for the given room ID so the user of the given client is not notified
+// about events in it anymore.
+func MuteRoom(ctx context.Context, cli matrix.Client, room string) error {
+	return Put(ctx, cli, KindRoom, Rule{ID: room}, "", "")
+}
+
+// UnmuteRoom removes the room rule for the given room ID that was added by MuteRoom.
+func UnmuteRoom(ctx context.Context, cli matrix.Client, room string) error {
+	return Delete(ctx, cli, KindRoom, room)
+}
+
+// AddKeyword adds a content rule that notifies the user of the given client with the default sound about messages
+// containing the given keyword. The keyword may contain * and ? as wildcards and is also used as rule ID.
+func AddKeyword(ctx context.Context, cli matrix.Client, keyword string) error {
+	rule := Rule{
+		ID:      keyword,
+		Pattern: keyword,
+		Actions: []Action{{Name: ActionNotify}, {Tweak: TweakSound, Value: []byte(`"default"`)}},
+	}
+
+	return Put(ctx, cli, KindContent, rule, "", "")
+}
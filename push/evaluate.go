@@ -0,0 +1,351 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package push
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"eqrx.net/matrix/event"
+)
+
+// defaultNotificationLevel is the power level required for notification keys missing in the power levels.
+const defaultNotificationLevel = 50
+
+// Kinds of rules in the order they are evaluated.
+const (
+	KindOverride  = "override"
+	KindContent   = "content"
+	KindRoom      = "room"
+	KindSender    = "sender"
+	KindUnderride = "underride"
+)
+
+// Context describes the user the rules belong to and the room of the evaluated event.
+type Context struct {
+	// User is the user ID of the owner of the rules.
+	User string
+	// DisplayName of the user in the room of the event.
+	DisplayName string
+	// MemberCount is the number of joined members of the room.
+	MemberCount int
+	// SenderPowerLevel is the power level of the sender of the event.
+	SenderPowerLevel int
+	// NotificationPowerLevels maps notification keys like "room" to the power level required to trigger them.
+	// Missing keys require a power level of 50.
+	NotificationPowerLevels map[string]int
+}
+
+// Result of evaluating rules against an event.
+type Result struct {
+	// Kind of the matching rule. Empty if no rule matched.
+	Kind string
+	// Rule that matched. Nil if no rule matched.
+	Rule *Rule
+	// Actions of the matching rule.
+	Actions []Action
+	// Notify is true if the user should be notified about the event.
+	Notify bool
+	// Highlight is true if the event should be highlighted.
+	Highlight bool
+	// Sound to play when notifying. Empty if no sound should be played.
+	Sound string
+	// Tweaks maps all tweaks set by the actions to their values.
+	Tweaks map[string]json.RawMessage
+}
+
+// Evaluate the rules against the given event in the given context. The first enabled rule that matches decides the
+// result. Returns an error if the event could not be marshalled.
+func (r Ruleset) Evaluate(evt event.Opaque, ctx Context) (Result, error) {
+	flat, err := newFlatEvent(evt)
+	if err != nil {
+		return Result{}, err
+	}
+
+	for _, kind := range []struct {
+		name  string
+		rules []Rule
+	}{
+		{KindOverride, r.Override},
+		{KindContent, r.Content},
+		{KindRoom, r.Room},
+		{KindSender, r.Sender},
+		{KindUnderride, r.Underride},
+	} {
+		for i := range kind.rules {
+			rule := &kind.rules[i]
+			if rule.Enabled && rule.matches(kind.name, evt, flat, ctx) {
+				return newResult(kind.name, rule), nil
+			}
+		}
+	}
+
+	return Result{}, nil
+}
+
+func newResult(kind string, rule *Rule) Result {
+	result := Result{Kind: kind, Rule: rule, Actions: rule.Actions, Tweaks: map[string]json.RawMessage{}}
+
+	for _, action := range rule.Actions {
+		switch {
+		case action.Name == ActionNotify:
+			result.Notify = true
+		case action.Tweak != "":
+			result.Tweaks[action.Tweak] = action.Value
+		}
+	}
+
+	if value, ok := result.Tweaks[TweakHighlight]; ok {
+		// A highlight tweak without value means true.
+		result.Highlight = len(value) == 0 || string(value) == "true"
+	}
+
+	if value, ok := result.Tweaks[TweakSound]; ok {
+		_ = json.Unmarshal(value, &result.Sound)
+	}
+
+	return result
+}
+
+func (r *Rule) matches(kind string, evt event.Opaque, flat flatEvent, ctx Context) bool {
+	switch kind {
+	case KindContent:
+		body, ok := flat.lookup("content.body")
+		str, isStr := body.(string)
+
+		return ok && isStr && matchGlob(r.Pattern, str, true)
+	case KindRoom:
+		return r.ID == evt.Room
+	case KindSender:
+		return r.ID == evt.Sender
+	}
+
+	for _, cond := range r.Conditions {
+		if !cond.matches(flat, ctx) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c Condition) matches(flat flatEvent, ctx Context) bool {
+	switch c.Kind {
+	case ConditionEventMatch:
+		value, ok := flat.lookup(c.Key)
+		str, isStr := value.(string)
+
+		return ok && isStr && matchGlob(c.Pattern, str, c.Key == "content.body")
+	case ConditionEventPropertyIs:
+		value, ok := flat.lookup(c.Key)
+
+		return ok && isScalar(value) && jsonEqual(value, c.Value)
+	case ConditionEventPropertyContains:
+		value, ok := flat.lookup(c.Key)
+		list, isList := value.([]interface{})
+
+		if !ok || !isList {
+			return false
+		}
+
+		for _, elem := range list {
+			if isScalar(elem) && jsonEqual(elem, c.Value) {
+				return true
+			}
+		}
+
+		return false
+	case ConditionContainsDisplayName:
+		body, ok := flat.lookup("content.body")
+		str, isStr := body.(string)
+
+		return ok && isStr && ctx.DisplayName != "" && matchWords(regexp.QuoteMeta(ctx.DisplayName), str)
+	case ConditionRoomMemberCount:
+		return matchMemberCount(c.Is, ctx.MemberCount)
+	case ConditionSenderNotificationPermission:
+		required, ok := ctx.NotificationPowerLevels[c.Key]
+		if !ok {
+			required = defaultNotificationLevel
+		}
+
+		return ctx.SenderPowerLevel >= required
+	default:
+		// Unknown conditions never match.
+		return false
+	}
+}
+
+// flatEvent is the generic JSON representation of an event used to look up keys of conditions.
+type flatEvent map[string]interface{}
+
+func newFlatEvent(evt event.Opaque) (flatEvent, error) {
+	encoded, err := json.Marshal(evt)
+	if err != nil {
+		return nil, fmt.Errorf("marshal event: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+
+	var flat flatEvent
+	if err := decoder.Decode(&flat); err != nil {
+		return nil, fmt.Errorf("unmarshal event: %w", err)
+	}
+
+	return flat, nil
+}
+
+// lookup returns the value of the given dotted key. Dots and backslashes that are part of a field name are
+// escaped with a backslash.
+func (f flatEvent) lookup(key string) (interface{}, bool) {
+	var current interface{} = map[string]interface{}(f)
+
+	for _, field := range splitKey(key) {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if current, ok = object[field]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func splitKey(key string) []string {
+	var (
+		fields  []string
+		current strings.Builder
+		escaped bool
+	)
+
+	for _, char := range key {
+		switch {
+		case escaped:
+			if char != '.' && char != '\\' {
+				current.WriteRune('\\')
+			}
+
+			current.WriteRune(char)
+
+			escaped = false
+		case char == '\\':
+			escaped = true
+		case char == '.':
+			fields = append(fields, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(char)
+		}
+	}
+
+	if escaped {
+		current.WriteRune('\\')
+	}
+
+	return append(fields, current.String())
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, bool, string, json.Number:
+		return true
+	default:
+		return false
+	}
+}
+
+// jsonEqual compares a value of a flat event with the raw value of a condition.
+func jsonEqual(value interface{}, raw json.RawMessage) bool {
+	if len(raw) == 0 {
+		return false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var other interface{}
+	if err := decoder.Decode(&other); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(value, other)
+}
+
+// globToRegexp converts a glob with * and ? to a regular expression without anchors.
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+
+	for _, char := range glob {
+		switch char {
+		case '*':
+			expr.WriteString(".*?")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	return expr.String()
+}
+
+// matchGlob matches the value case insensitive against the glob. If words is true the glob may match any part of
+// value that is delimited by word boundaries, otherwise it has to match the whole value.
+func matchGlob(glob, value string, words bool) bool {
+	if words {
+		return matchWords(globToRegexp(glob), value)
+	}
+
+	expr, err := regexp.Compile(`(?is)^` + globToRegexp(glob) + `$`)
+
+	return err == nil && expr.MatchString(value)
+}
+
+func matchWords(expr, value string) bool {
+	compiled, err := regexp.Compile(`(?is)(?:^|\W)` + expr + `(?:\W|$)`)
+
+	return err == nil && compiled.MatchString(value)
+}
+
+func matchMemberCount(is string, count int) bool {
+	operator := strings.TrimRight(is, "0123456789")
+
+	number, err := strconv.Atoi(is[len(operator):])
+	if err != nil {
+		return false
+	}
+
+	switch operator {
+	case "", "==":
+		return count == number
+	case "<":
+		return count < number
+	case ">":
+		return count > number
+	case "<=":
+		return count <= number
+	case ">=":
+		return count >= number
+	default:
+		return false
+	}
+}
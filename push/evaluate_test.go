@@ -0,0 +1,173 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+package push_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"eqrx.net/matrix/event"
+	"eqrx.net/matrix/push"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	const (
+		notify     = `"actions":["notify"]`
+		message    = `{"type":"m.room.message","room_id":"!r:d","sender":"@bob:d","content":{"body":%s}}`
+		dotted     = `{"type":"m.room.message","sender":"@bob:d","content":{"m.key":"value","m":{"key":"other"}}}`
+		properties = `{"type":"x","sender":"@bob:d","content":{"number":5,"string":"5","list":["a",5,true]}}`
+	)
+
+	body := func(text string) string {
+		encoded, _ := json.Marshal(text)
+
+		return fmt.Sprintf(message, encoded)
+	}
+
+	override := func(conditions string) string {
+		return `{"override":[{"rule_id":"r","enabled":true,` + notify + `,"conditions":[` + conditions + `]}]}`
+	}
+
+	for _, test := range []struct {
+		name, rules, event string
+		ctx                push.Context
+		kind               string
+	}{
+		// Content rules match words of the body.
+		{"content word", `{"content":[{"rule_id":"c","enabled":true,"pattern":"cake",` + notify + `}]}`,
+			body("I like Cake!"), push.Context{}, push.KindContent},
+		{"content part of word", `{"content":[{"rule_id":"c","enabled":true,"pattern":"cake",` + notify + `}]}`,
+			body("cupcakes"), push.Context{}, ""},
+		{"content glob", `{"content":[{"rule_id":"c","enabled":true,"pattern":"cake*",` + notify + `}]}`,
+			body("cakes are nice"), push.Context{}, push.KindContent},
+		{"content disabled", `{"content":[{"rule_id":"c","enabled":false,"pattern":"cake",` + notify + `}]}`,
+			body("cake"), push.Context{}, ""},
+		// event_match matches words only for content.body and the whole value otherwise.
+		{"body words", override(`{"kind":"event_match","key":"content.body","pattern":"cake"}`),
+			body("lovely cake"), push.Context{}, push.KindOverride},
+		{"whole value glob", override(`{"kind":"event_match","key":"type","pattern":"m.room.*"}`),
+			body(""), push.Context{}, push.KindOverride},
+		{"whole value only", override(`{"kind":"event_match","key":"type","pattern":"room"}`),
+			body(""), push.Context{}, ""},
+		{"whole value single char", override(`{"kind":"event_match","key":"sender","pattern":"@???:d"}`),
+			body(""), push.Context{}, push.KindOverride},
+		// Dots in field names are escaped with a backslash.
+		{"escaped key", override(`{"kind":"event_match","key":"content.m\\.key","pattern":"value"}`),
+			dotted, push.Context{}, push.KindOverride},
+		{"unescaped key", override(`{"kind":"event_match","key":"content.m.key","pattern":"value"}`),
+			dotted, push.Context{}, ""},
+		{"nested key", override(`{"kind":"event_match","key":"content.m.key","pattern":"other"}`),
+			dotted, push.Context{}, push.KindOverride},
+		{"property is number", override(`{"kind":"event_property_is","key":"content.number","value":5}`),
+			properties, push.Context{}, push.KindOverride},
+		{"property is type", override(`{"kind":"event_property_is","key":"content.number","value":"5"}`),
+			properties, push.Context{}, ""},
+		{"property contains", override(`{"kind":"event_property_contains","key":"content.list","value":true}`),
+			properties, push.Context{}, push.KindOverride},
+		{"property contains not", override(`{"kind":"event_property_contains","key":"content.list","value":"b"}`),
+			properties, push.Context{}, ""},
+		// Member count operators.
+		{"count equal", override(`{"kind":"room_member_count","is":"2"}`),
+			body(""), push.Context{MemberCount: 2}, push.KindOverride},
+		{"count equal explicit", override(`{"kind":"room_member_count","is":"==3"}`),
+			body(""), push.Context{MemberCount: 2}, ""},
+		{"count less", override(`{"kind":"room_member_count","is":"<2"}`),
+			body(""), push.Context{MemberCount: 2}, ""},
+		{"count greater equal", override(`{"kind":"room_member_count","is":">=2"}`),
+			body(""), push.Context{MemberCount: 2}, push.KindOverride},
+		{"count invalid", override(`{"kind":"room_member_count","is":"=>2"}`),
+			body(""), push.Context{MemberCount: 2}, ""},
+		// Display names match as words.
+		{"display name", override(`{"kind":"contains_display_name"}`),
+			body("hey alice!"), push.Context{DisplayName: "Alice"}, push.KindOverride},
+		{"display name part", override(`{"kind":"contains_display_name"}`),
+			body("malice"), push.Context{DisplayName: "Alice"}, ""},
+		{"display name empty", override(`{"kind":"contains_display_name"}`),
+			body("hey"), push.Context{}, ""},
+		// Notification permissions default to 50.
+		{"permission default", override(`{"kind":"sender_notification_permission","key":"room"}`),
+			body(""), push.Context{SenderPowerLevel: 50}, push.KindOverride},
+		{"permission missing", override(`{"kind":"sender_notification_permission","key":"room"}`),
+			body(""), push.Context{SenderPowerLevel: 49}, ""},
+		{"permission set", override(`{"kind":"sender_notification_permission","key":"room"}`),
+			body(""), push.Context{SenderPowerLevel: 10, NotificationPowerLevels: map[string]int{"room": 10}},
+			push.KindOverride},
+		{"unknown condition", override(`{"kind":"org.example.unknown"}`), body(""), push.Context{}, ""},
+		// Room and sender rules match by ID, kinds are evaluated in order.
+		{"room", `{"room":[{"rule_id":"!r:d","enabled":true,` + notify + `}],` +
+			`"sender":[{"rule_id":"@bob:d","enabled":true,` + notify + `}]}`, body(""), push.Context{}, push.KindRoom},
+		{"sender", `{"sender":[{"rule_id":"@bob:d","enabled":true,` + notify + `}]}`,
+			body(""), push.Context{}, push.KindSender},
+	} {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := evaluate(t, test.rules, test.event, test.ctx)
+			if result.Kind != test.kind {
+				t.Errorf("got kind %q, want %q", result.Kind, test.kind)
+			}
+		})
+	}
+}
+
+func TestEvaluateTweaks(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name, actions     string
+		notify, highlight bool
+		sound             string
+	}{
+		{"notify", `["notify"]`, true, false, ""},
+		{"dont notify", `["dont_notify"]`, false, false, ""},
+		{"highlight without value", `["notify",{"set_tweak":"highlight"}]`, true, true, ""},
+		{"highlight true", `["notify",{"set_tweak":"highlight","value":true}]`, true, true, ""},
+		{"highlight false", `["notify",{"set_tweak":"highlight","value":false}]`, true, false, ""},
+		{"sound", `["notify",{"set_tweak":"sound","value":"default"}]`, true, false, "default"},
+	} {
+		rules := `{"underride":[{"rule_id":"u","enabled":true,"actions":` + test.actions + `}]}`
+		result := evaluate(t, rules, `{"type":"x","content":{}}`, push.Context{})
+
+		if result.Notify != test.notify || result.Highlight != test.highlight || result.Sound != test.sound {
+			t.Errorf("%s: got notify %v, highlight %v, sound %q, want %v, %v, %q", test.name,
+				result.Notify, result.Highlight, result.Sound, test.notify, test.highlight, test.sound)
+		}
+	}
+}
+
+// evaluate evaluates the given ruleset JSON against the given event JSON.
+func evaluate(t *testing.T, rules, evt string, ctx push.Context) push.Result {
+	t.Helper()
+
+	var ruleset push.Ruleset
+	if err := json.Unmarshal([]byte(rules), &ruleset); err != nil {
+		t.Fatalf("unmarshal rules: %v", err)
+	}
+
+	var opaque event.Opaque
+	if err := json.Unmarshal([]byte(evt), &opaque); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+
+	result, err := ruleset.Evaluate(opaque, ctx)
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+
+	return result
+}
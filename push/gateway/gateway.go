@@ -0,0 +1,151 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+// Package gateway implements a matrix push gateway that passes notifications of homeservers to a callback.
+// Register it with an http pusher whose data URL points to Path on the server running the gateway.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Path of the notify endpoint of the push gateway API.
+const Path = "/_matrix/push/v1/notify"
+
+// maxBodySize is the maximum size of a notify request body. Notifications are small, homeservers limit event
+// content to 64 KiB.
+const maxBodySize = 256 << 10
+
+// Priorities of notifications.
+const (
+	PriorityHigh = "high"
+	PriorityLow  = "low"
+)
+
+// Notification sent by a homeserver. Event fields are empty if the pusher uses the event_id_only format.
+type Notification struct {
+	EventID           string          `json:"event_id"`
+	RoomID            string          `json:"room_id"`
+	Type              string          `json:"type"`
+	Sender            string          `json:"sender"`
+	SenderDisplayName string          `json:"sender_display_name"`
+	RoomName          string          `json:"room_name"`
+	RoomAlias         string          `json:"room_alias"`
+	UserIsTarget      bool            `json:"user_is_target"`
+	Priority          string          `json:"prio"`
+	Content           json.RawMessage `json:"content"`
+	Counts            Counts          `json:"counts"`
+	Devices           []Device        `json:"devices"`
+}
+
+// Counts of unread items of the notified user.
+type Counts struct {
+	Unread      int `json:"unread"`
+	MissedCalls int `json:"missed_calls"`
+}
+
+// Device the notification is addressed to. Data is the data of the pusher minus url and format.
+type Device struct {
+	AppID     string                     `json:"app_id"`
+	PushKey   string                     `json:"pushkey"`
+	PushKeyTS int64                      `json:"pushkey_ts"`
+	Data      map[string]json.RawMessage `json:"data"`
+	Tweaks    map[string]json.RawMessage `json:"tweaks"`
+}
+
+// Handler is called for each notification. Devices of the notification only contain devices with known push keys.
+// Returning an error lets the homeserver retry the notification later.
+type Handler func(ctx context.Context, notification Notification) error
+
+// Gateway is a http.Handler serving the notify endpoint of the push gateway API.
+type Gateway struct {
+	pushKeys map[string]struct{}
+	handle   Handler
+}
+
+// New creates a gateway that accepts notifications for the given push keys and passes them to the given handler.
+// Devices with other push keys are rejected, which makes homeservers remove the corresponding pushers.
+func New(handle Handler, pushKeys ...string) *Gateway {
+	if handle == nil || len(pushKeys) == 0 {
+		panic("parameter empty")
+	}
+
+	gateway := &Gateway{map[string]struct{}{}, handle}
+	for _, key := range pushKeys {
+		gateway.pushKeys[key] = struct{}{}
+	}
+
+	return gateway
+}
+
+type notifyRequest struct {
+	Notification Notification `json:"notification"`
+}
+
+type notifyResponse struct {
+	Rejected []string `json:"rejected"`
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != Path {
+		http.NotFound(writer, request)
+
+		return
+	}
+
+	if request.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	var notify notifyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, maxBodySize)).Decode(&notify); err != nil {
+		http.Error(writer, "invalid notification", http.StatusBadRequest)
+
+		return
+	}
+
+	notification := notify.Notification
+	response := notifyResponse{Rejected: []string{}}
+	devices := make([]Device, 0, len(notification.Devices))
+
+	for _, device := range notification.Devices {
+		if _, ok := g.pushKeys[device.PushKey]; !ok {
+			response.Rejected = append(response.Rejected, device.PushKey)
+
+			continue
+		}
+
+		devices = append(devices, device)
+	}
+
+	if len(devices) != 0 {
+		notification.Devices = devices
+
+		if err := g.handle(request.Context(), notification); err != nil {
+			http.Error(writer, "handle notification", http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	// Nothing sensible can be done if writing the response fails.
+	_ = json.NewEncoder(writer).Encode(response)
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package push
+
+import (
+	"context"
+	"net/http"
+
+	"eqrx.net/matrix"
+)
+
+// Kinds of pushers.
+const (
+	PusherKindHTTP  = "http"
+	PusherKindEmail = "email"
+)
+
+// Pusher forwards notifications of a user to a push gateway.
+type Pusher struct {
+	PushKey           string     `json:"pushkey"`
+	Kind              string     `json:"kind"`
+	AppID             string     `json:"app_id"`
+	AppDisplayName    string     `json:"app_display_name"`
+	DeviceDisplayName string     `json:"device_display_name"`
+	ProfileTag        string     `json:"profile_tag,omitempty"`
+	Lang              string     `json:"lang"`
+	Data              PusherData `json:"data"`
+}
+
+// PusherData configures how a pusher contacts the push gateway. URL must be set for http pushers and point to the
+// notify endpoint of the gateway.
+type PusherData struct {
+	URL    string `json:"url,omitempty"`
+	Format string `json:"format,omitempty"`
+}
+
+// Pushers returns all pushers of the user of the given client.
+func Pushers(ctx context.Context, cli matrix.Client) ([]Pusher, error) {
+	var response struct {
+		matrix.Response
+		Pushers []Pusher `json:"pushers"`
+	}
+
+	err := exchange(ctx, cli, http.MethodGet, "/_matrix/client/v3/pushers", nil, &response)
+
+	return response.Pushers, err
+}
+
+type setPusherRequest struct {
+	Pusher
+	Append bool `json:"append,omitempty"`
+}
+
+// SetPusher creates or updates the given pusher for the user of the given client. Pushers with the same app ID and
+// push key are replaced. If appendPusher is false, pushers with the same push key of other users are removed.
+func SetPusher(ctx context.Context, cli matrix.Client, pusher Pusher, appendPusher bool) error {
+	if pusher.PushKey == "" || pusher.AppID == "" || pusher.Kind == "" {
+		panic("parameter empty")
+	}
+
+	request := setPusherRequest{pusher, appendPusher}
+
+	return exchange(ctx, cli, http.MethodPost, "/_matrix/client/v3/pushers/set", request, nil)
+}
+
+type removePusherRequest struct {
+	PushKey string  `json:"pushkey"`
+	AppID   string  `json:"app_id"`
+	Kind    *string `json:"kind"`
+}
+
+// RemovePusher removes the pusher with the given app ID and push key of the user of the given client.
+func RemovePusher(ctx context.Context, cli matrix.Client, appID, pushKey string) error {
+	if appID == "" || pushKey == "" {
+		panic("parameter empty")
+	}
+
+	return exchange(
+		ctx, cli, http.MethodPost, "/_matrix/client/v3/pushers/set", removePusherRequest{pushKey, appID, nil}, nil,
+	)
+}
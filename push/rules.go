@@ -0,0 +1,133 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+// Package push handles push rules that decide which events notify a user.
+package push
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"eqrx.net/matrix/event"
+)
+
+// EventTypePushRules in a event type field indicates that the account data event contains the push rules of the user.
+const EventTypePushRules = "m.push_rules"
+
+//nolint:gochecknoinits // FromAccountData decodes the push rules with event.As, which needs the type.
+func init() {
+	event.Register[RulesContent](EventTypePushRules)
+}
+
+// RulesContent represents the content of the push rules account data event.
+type RulesContent struct {
+	Global Ruleset `json:"global"`
+}
+
+// Ruleset contains push rules grouped by kind. Kinds are evaluated in the order of the fields.
+type Ruleset struct {
+	Override  []Rule `json:"override,omitempty"`
+	Content   []Rule `json:"content,omitempty"`
+	Room      []Rule `json:"room,omitempty"`
+	Sender    []Rule `json:"sender,omitempty"`
+	Underride []Rule `json:"underride,omitempty"`
+}
+
+// Rule is a single push rule. Conditions are only used by override and underride rules, Pattern only by content rules.
+// The ID of room rules is the room ID they apply to and the ID of sender rules the user ID.
+type Rule struct {
+	ID         string      `json:"rule_id"`
+	Default    bool        `json:"default"`
+	Enabled    bool        `json:"enabled"`
+	Actions    []Action    `json:"actions"`
+	Conditions []Condition `json:"conditions,omitempty"`
+	Pattern    string      `json:"pattern,omitempty"`
+}
+
+// Kinds of conditions.
+const (
+	ConditionEventMatch                   = "event_match"
+	ConditionEventPropertyIs              = "event_property_is"
+	ConditionEventPropertyContains        = "event_property_contains"
+	ConditionContainsDisplayName          = "contains_display_name"
+	ConditionRoomMemberCount              = "room_member_count"
+	ConditionSenderNotificationPermission = "sender_notification_permission"
+)
+
+// Condition that has to be met for a rule to match. Which fields are used depends on the kind.
+type Condition struct {
+	Kind    string          `json:"kind"`
+	Key     string          `json:"key,omitempty"`
+	Pattern string          `json:"pattern,omitempty"`
+	Is      string          `json:"is,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+}
+
+// Names of simple actions.
+const (
+	ActionNotify     = "notify"
+	ActionDontNotify = "dont_notify"
+	ActionCoalesce   = "coalesce"
+)
+
+// Names of tweaks.
+const (
+	TweakSound     = "sound"
+	TweakHighlight = "highlight"
+)
+
+// Action to perform when a rule matches. Either Name is set for simple actions or Tweak and optionally Value
+// for actions that set a tweak.
+type Action struct {
+	Name  string
+	Tweak string
+	Value json.RawMessage
+}
+
+type tweak struct {
+	Tweak string          `json:"set_tweak"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// UnmarshalJSON decodes either a simple or a tweak action.
+func (a *Action) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*a = Action{Name: name}
+
+		return nil
+	}
+
+	var t tweak
+	if err := json.Unmarshal(b, &t); err != nil {
+		return fmt.Errorf("unmarshal push action: %w", err)
+	}
+
+	*a = Action{Tweak: t.Tweak, Value: t.Value}
+
+	return nil
+}
+
+// MarshalJSON encodes either a simple or a tweak action.
+func (a Action) MarshalJSON() ([]byte, error) {
+	if a.Tweak == "" {
+		return json.Marshal(a.Name)
+	}
+
+	return json.Marshal(tweak{a.Tweak, a.Value})
+}
+
+var (
+	_ json.Unmarshaler = &Action{}
+	_ json.Marshaler   = Action{}
+)
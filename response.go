@@ -13,20 +13,46 @@
 
 package matrix
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // Response is the base for all HTTP responses returned by a matrix server.
 type Response struct {
-	ErrCode string `json:"errcode"`
-	ErrMsg  string `json:"error"`
+	ErrCode string `json:"errcode,omitempty"`
+	ErrMsg  string `json:"error,omitempty"`
 }
 
 // AsError returns a descriptive error instance if the matrix server has included an error
 // in the response. Returns nil otherwise.
 func (r Response) AsError() error {
 	if r.ErrCode != "" || r.ErrMsg != "" {
-		return fmt.Errorf("matrix server response: %s: %s", r.ErrCode, r.ErrMsg)
+		return &Error{Code: r.ErrCode, Message: r.ErrMsg}
 	}
 
 	return nil
 }
+
+// Error is returned if the matrix server indicated that a request failed. Status is the HTTP status code
+// of the response or zero if the error was included in a successful response.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Status == 0 {
+		return fmt.Sprintf("matrix server response: %s: %s", e.Code, e.Message)
+	}
+
+	return fmt.Sprintf("matrix server response: status code %d: %s: %s", e.Status, e.Code, e.Message)
+}
+
+// IsErrorCode returns true if err is or wraps an Error with the given matrix error code like M_NOT_FOUND.
+func IsErrorCode(err error, code string) bool {
+	var mErr *Error
+
+	return errors.As(err, &mErr) && mErr.Code == code
+}
@@ -0,0 +1,200 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"eqrx.net/matrix"
+)
+
+func aliasPath(alias string) string {
+	if !strings.HasPrefix(alias, "#") {
+		panic("not an alias")
+	}
+
+	return "/_matrix/client/v3/directory/room/" + url.PathEscape(alias)
+}
+
+// ResolveAlias returns the ID of the room the given alias points to and server names that may be used to join it.
+func ResolveAlias(ctx context.Context, cli matrix.Client, alias string) (ID, []string, error) {
+	var response struct {
+		matrix.Response
+		ID      ID       `json:"room_id"`
+		Servers []string `json:"servers"`
+	}
+
+	if err := cli.HTTP(ctx, http.MethodGet, aliasPath(alias), nil, &response); err != nil {
+		return "", nil, fmt.Errorf("resolve alias %s: %w", alias, err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return "", nil, err
+	}
+
+	return response.ID, response.Servers, nil
+}
+
+// Resolve returns the room ID the given alias points to. Values that are not aliases are returned as they are, so
+// all functions of this package that take a room ID accept an alias as well.
+func Resolve(ctx context.Context, cli matrix.Client, idOrAlias ID) (ID, error) {
+	if !strings.HasPrefix(string(idOrAlias), "#") {
+		return idOrAlias, nil
+	}
+
+	id, _, err := ResolveAlias(ctx, cli, string(idOrAlias))
+
+	return id, err
+}
+
+// roomPath resolves the given room ID or alias and returns the client API path of the room with the given path
+// elements appended. Elements are escaped.
+func roomPath(ctx context.Context, cli matrix.Client, idOrAlias ID, elems ...string) (string, error) {
+	if idOrAlias == "" {
+		panic("room id empty")
+	}
+
+	id, err := Resolve(ctx, cli, idOrAlias)
+	if err != nil {
+		return "", err
+	}
+
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(string(id))
+	for _, elem := range elems {
+		path += "/" + url.PathEscape(elem)
+	}
+
+	return path, nil
+}
+
+// CreateAlias creates the given alias pointing to the given room ID with the client.
+func CreateAlias(ctx context.Context, cli matrix.Client, alias string, id ID) error {
+	if id == "" {
+		panic("room id empty")
+	}
+
+	request := struct {
+		ID ID `json:"room_id"`
+	}{id}
+
+	var response matrix.Response
+
+	if err := cli.HTTP(ctx, http.MethodPut, aliasPath(alias), request, &response); err != nil {
+		return fmt.Errorf("create alias %s: %w", alias, err)
+	}
+
+	return response.AsError()
+}
+
+// DeleteAlias deletes the given alias with the client.
+func DeleteAlias(ctx context.Context, cli matrix.Client, alias string) error {
+	var response matrix.Response
+
+	if err := cli.HTTP(ctx, http.MethodDelete, aliasPath(alias), nil, &response); err != nil {
+		return fmt.Errorf("delete alias %s: %w", alias, err)
+	}
+
+	return response.AsError()
+}
+
+// Aliases returns the local aliases of the given room ID or alias.
+func Aliases(ctx context.Context, cli matrix.Client, id ID) ([]string, error) {
+	path, err := roomPath(ctx, cli, id, "aliases")
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		matrix.Response
+		Aliases []string `json:"aliases"`
+	}
+
+	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return nil, fmt.Errorf("list aliases: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return nil, err
+	}
+
+	return response.Aliases, nil
+}
+
+type visibilityBody struct {
+	matrix.Response
+	Visibility string `json:"visibility"`
+}
+
+func directoryListPath(ctx context.Context, cli matrix.Client, id ID) (string, error) {
+	if id == "" {
+		panic("room id empty")
+	}
+
+	resolved, err := Resolve(ctx, cli, id)
+	if err != nil {
+		return "", err
+	}
+
+	return "/_matrix/client/v3/directory/list/room/" + url.PathEscape(string(resolved)), nil
+}
+
+// DirectoryVisibility returns if the given room ID or alias is listed in the public room directory. Returns either
+// VisibilityPublic or VisibilityPrivate.
+func DirectoryVisibility(ctx context.Context, cli matrix.Client, id ID) (string, error) {
+	path, err := directoryListPath(ctx, cli, id)
+	if err != nil {
+		return "", err
+	}
+
+	var response visibilityBody
+
+	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return "", fmt.Errorf("get directory visibility: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return "", err
+	}
+
+	return response.Visibility, nil
+}
+
+// SetDirectoryVisibility sets if the given room ID or alias is listed in the public room directory. Visibility is
+// either VisibilityPublic or VisibilityPrivate.
+func SetDirectoryVisibility(ctx context.Context, cli matrix.Client, id ID, visibility string) error {
+	if visibility != VisibilityPublic && visibility != VisibilityPrivate {
+		panic("invalid visibility")
+	}
+
+	path, err := directoryListPath(ctx, cli, id)
+	if err != nil {
+		return err
+	}
+
+	var response matrix.Response
+
+	request := struct {
+		Visibility string `json:"visibility"`
+	}{visibility}
+
+	if err := cli.HTTP(ctx, http.MethodPut, path, request, &response); err != nil {
+		return fmt.Errorf("set directory visibility: %w", err)
+	}
+
+	return response.AsError()
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"eqrx.net/matrix"
+	"eqrx.net/matrix/event"
+)
+
+// ID of a room. Functions of this package that take an ID also accept a room alias converted to ID, see Resolve.
+type ID string
+
+// String returns the ID as string.
+func (i ID) String() string { return string(i) }
+
+// Presets for room creation. They set join rules, history visibility and guest access of the created room.
+const (
+	PresetPrivateChat        = "private_chat"
+	PresetTrustedPrivateChat = "trusted_private_chat"
+	PresetPublicChat         = "public_chat"
+)
+
+// Visibilities of a room in the room directory.
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// CreateRequest describes a room to create. All fields are optional.
+type CreateRequest struct {
+	Preset     string `json:"preset,omitempty"`
+	Visibility string `json:"visibility,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Topic      string `json:"topic,omitempty"`
+	// AliasLocalpart is the local part of an alias to create for the room on the server of the client.
+	AliasLocalpart  string         `json:"room_alias_name,omitempty"`
+	Invite          []string       `json:"invite,omitempty"`
+	Invite3PID      []Invite3PID   `json:"invite_3pid,omitempty"`
+	Version         string         `json:"room_version,omitempty"`
+	CreationContent *CreateContent `json:"creation_content,omitempty"`
+	// InitialState is applied after the preset but before name and topic.
+	InitialState []InitialState `json:"initial_state,omitempty"`
+	// PowerLevelContentOverride is merged into the power levels the server generates. Only set fields are overridden.
+	PowerLevelContentOverride *PowerLevelsContent `json:"power_level_content_override,omitempty"`
+	IsDirect                  bool                `json:"is_direct,omitempty"`
+}
+
+// Invite3PID invites a user by a third party identifier like an email address.
+type Invite3PID struct {
+	IDServer      string `json:"id_server"`
+	IDAccessToken string `json:"id_access_token"`
+	Medium        string `json:"medium"`
+	Address       string `json:"address"`
+}
+
+// InitialState is a state event to set while creating a room. Content is usually one of the state content types of
+// this package, like JoinRulesContent for EventTypeJoinRules. Use NewInitialState to ensure both fit together.
+type InitialState struct {
+	Type     string      `json:"type"`
+	StateKey string      `json:"state_key"`
+	Content  interface{} `json:"content"`
+}
+
+// NewInitialState creates an initial state event of the given type and state key with the given content. Returns an
+// error if T is not the type registered for the event type or the state key does not fit the event type.
+func NewInitialState[T any](eventType, stateKey string, content T) (InitialState, error) {
+	if err := event.CheckType[T](eventType); err != nil {
+		return InitialState{}, err
+	}
+
+	if err := checkStateKey(event.Metadata{Type: eventType, StateKey: &stateKey}); err != nil {
+		return InitialState{}, err
+	}
+
+	return InitialState{eventType, stateKey, content}, nil
+}
+
+type createResponse struct {
+	matrix.Response
+	ID ID `json:"room_id"`
+}
+
+// Create a room as described by the given request with the given client. Returns the ID of the created room.
+func Create(ctx context.Context, cli matrix.Client, request CreateRequest) (ID, error) {
+	for _, state := range request.InitialState {
+		if state.Type == "" || state.Content == nil {
+			panic("initial state incomplete")
+		}
+	}
+
+	var response createResponse
+
+	if err := cli.HTTP(ctx, http.MethodPost, "/_matrix/client/v3/createRoom", request, &response); err != nil {
+		return "", fmt.Errorf("create room: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return "", err
+	}
+
+	return response.ID, nil
+}
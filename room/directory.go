@@ -0,0 +1,168 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"eqrx.net/matrix"
+)
+
+// PublicRoomsQuery selects rooms of a public room directory. All fields are optional.
+type PublicRoomsQuery struct {
+	// Server whose directory is browsed. The server of the client is used if empty.
+	Server string
+	// SearchTerm is matched against name, topic and alias of rooms.
+	SearchTerm string
+	// RoomTypes restricts the result to rooms of the given types. An empty string selects rooms without type.
+	RoomTypes []string
+	// ThirdPartyInstanceID selects rooms of a third party network.
+	ThirdPartyInstanceID string
+	// IncludeAllNetworks selects rooms of all third party networks.
+	IncludeAllNetworks bool
+	// Limit is the maximum number of rooms per page.
+	Limit int
+}
+
+// PublicRoom is a room listed in a public room directory.
+type PublicRoom struct {
+	ID               ID     `json:"room_id"`
+	Name             string `json:"name"`
+	Topic            string `json:"topic"`
+	CanonicalAlias   string `json:"canonical_alias"`
+	AvatarURL        string `json:"avatar_url"`
+	JoinRule         string `json:"join_rule"`
+	RoomType         string `json:"room_type"`
+	NumJoinedMembers int    `json:"num_joined_members"`
+	WorldReadable    bool   `json:"world_readable"`
+	GuestCanJoin     bool   `json:"guest_can_join"`
+}
+
+// PublicRooms pages through a public room directory.
+type PublicRooms struct {
+	cli   matrix.Client
+	query PublicRoomsQuery
+	since string
+	done  bool
+	total int
+}
+
+// NewPublicRooms creates a new PublicRooms that pages through the rooms selected by the given query with the
+// given client.
+func NewPublicRooms(cli matrix.Client, query PublicRoomsQuery) *PublicRooms {
+	return &PublicRooms{cli: cli, query: query}
+}
+
+type publicRoomsFilter struct {
+	SearchTerm string        `json:"generic_search_term,omitempty"`
+	RoomTypes  []interface{} `json:"room_types,omitempty"`
+}
+
+type publicRoomsRequest struct {
+	Filter               *publicRoomsFilter `json:"filter,omitempty"`
+	IncludeAllNetworks   bool               `json:"include_all_networks,omitempty"`
+	Limit                int                `json:"limit,omitempty"`
+	Since                string             `json:"since,omitempty"`
+	ThirdPartyInstanceID string             `json:"third_party_instance_id,omitempty"`
+}
+
+type publicRoomsResponse struct {
+	matrix.Response
+	Chunk     []PublicRoom `json:"chunk"`
+	NextBatch string       `json:"next_batch"`
+	Total     int          `json:"total_room_count_estimate"`
+}
+
+func (p *PublicRooms) request() (string, string, interface{}) {
+	query := url.Values{}
+	if p.query.Server != "" {
+		query.Set("server", p.query.Server)
+	}
+
+	needsPost := p.query.SearchTerm != "" || len(p.query.RoomTypes) != 0 || p.query.ThirdPartyInstanceID != "" ||
+		p.query.IncludeAllNetworks
+
+	if !needsPost {
+		if p.query.Limit != 0 {
+			query.Set("limit", strconv.Itoa(p.query.Limit))
+		}
+
+		if p.since != "" {
+			query.Set("since", p.since)
+		}
+	}
+
+	path := "/_matrix/client/v3/publicRooms"
+	if len(query) != 0 {
+		path += "?" + query.Encode()
+	}
+
+	if !needsPost {
+		return http.MethodGet, path, nil
+	}
+
+	request := publicRoomsRequest{
+		IncludeAllNetworks:   p.query.IncludeAllNetworks,
+		Limit:                p.query.Limit,
+		Since:                p.since,
+		ThirdPartyInstanceID: p.query.ThirdPartyInstanceID,
+	}
+
+	if p.query.SearchTerm != "" || len(p.query.RoomTypes) != 0 {
+		request.Filter = &publicRoomsFilter{SearchTerm: p.query.SearchTerm}
+
+		for _, roomType := range p.query.RoomTypes {
+			if roomType == "" {
+				request.Filter.RoomTypes = append(request.Filter.RoomTypes, nil)
+			} else {
+				request.Filter.RoomTypes = append(request.Filter.RoomTypes, roomType)
+			}
+		}
+	}
+
+	return http.MethodPost, path, request
+}
+
+// Next returns the next page of rooms. Returns io.EOF after the last page.
+func (p *PublicRooms) Next(ctx context.Context) ([]PublicRoom, error) {
+	if p.done {
+		return nil, io.EOF
+	}
+
+	method, path, request := p.request()
+
+	var response publicRoomsResponse
+
+	if err := p.cli.HTTP(ctx, method, path, request, &response); err != nil {
+		return nil, fmt.Errorf("list public rooms: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return nil, err
+	}
+
+	p.since = response.NextBatch
+	p.done = response.NextBatch == ""
+	p.total = response.Total
+
+	return response.Chunk, nil
+}
+
+// Total returns the estimated number of rooms in the directory as reported with the last page.
+func (p *PublicRooms) Total() int { return p.total }
@@ -0,0 +1,163 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"eqrx.net/matrix"
+	"eqrx.net/matrix/event"
+	"eqrx.net/matrix/sync"
+)
+
+// EventTypeFullyRead in a event type field indicates that the room account data event is a fully read marker.
+const EventTypeFullyRead = "m.fully_read"
+
+// IsFullyReadEvent returns true if the given event metadata indicates that the event is a fully read marker.
+func IsFullyReadEvent(evt event.Metadata) bool {
+	return evt.Type == EventTypeFullyRead
+}
+
+// FullyReadEvent is the room account data event that tracks up to where the user has read a room.
+type FullyReadEvent struct {
+	event.Metadata
+	Content FullyReadContent `json:"content"`
+}
+
+// FullyReadContent represents the content of a fully read marker.
+type FullyReadContent struct {
+	ID string `json:"event_id"`
+}
+
+// AsFullyReadEvent converts the given opaque event to a fully read marker. Returns an error if metadata indicates that
+// the event is not a fully read marker or if unmarshalling of the content failed.
+func AsFullyReadEvent(evt event.Opaque) (FullyReadEvent, error) {
+	content, err := event.As[FullyReadContent](evt)
+
+	return FullyReadEvent{evt.Metadata, content}, err
+}
+
+// Markers to set for a room. Empty fields are not changed on the server.
+type Markers struct {
+	// FullyRead is the event up to which the user has read the room. Only visible to the user itself.
+	FullyRead string `json:"m.fully_read,omitempty"`
+	// Read is the event up to which the user has read the room as public read receipt.
+	Read string `json:"m.read,omitempty"`
+	// ReadPrivate is the event up to which the user has read the room as read receipt not shared with others.
+	ReadPrivate string `json:"m.read.private,omitempty"`
+}
+
+// SetMarkers updates the read markers of the given room ID or alias with the client.
+func SetMarkers(ctx context.Context, cli matrix.Client, id ID, markers Markers) error {
+	if markers == (Markers{}) {
+		panic("markers empty")
+	}
+
+	path, err := roomPath(ctx, cli, id, "read_markers")
+	if err != nil {
+		return err
+	}
+
+	var response matrix.Response
+
+	if err := cli.HTTP(ctx, http.MethodPost, path, markers, &response); err != nil {
+		return fmt.Errorf("set read markers: %w", err)
+	}
+
+	return response.AsError()
+}
+
+// Unread contains the events of a joined room the user has not read yet, according to the fully read marker
+// and the notification counts reported by the server.
+type Unread struct {
+	// MarkerFound is false if the fully read marker is not part of the timeline. In that case the timeline either
+	// was limited or contains no event the user has read, so all events given are unread.
+	MarkerFound bool
+	// Events not part of a thread that are located after the marker.
+	Events []event.Opaque
+	// Threads maps thread root event IDs to the events of that thread that are located after the marker.
+	Threads map[string][]event.Opaque
+	// Counts are the notification counts of the room not belonging to any thread.
+	Counts sync.UnreadNotificationsCounts
+	// ThreadCounts maps thread root event IDs to the notification counts of the thread.
+	ThreadCounts map[string]sync.UnreadNotificationsCounts
+}
+
+type threadRelation struct {
+	RelatesTo struct {
+		Type string `json:"rel_type"`
+		ID   string `json:"event_id"`
+	} `json:"m.relates_to"`
+}
+
+// UnreadEvents determines the unread events of the given joined room. The marker is the ID of the last event the user
+// has read. If it is empty, the fully read marker is taken from the room account data of the joined room. Events
+// sent by user are never considered unread.
+func UnreadEvents(room sync.JoinedRoom, user, marker string) (Unread, error) {
+	if marker == "" {
+		for _, evt := range room.AccountData.Events {
+			if !IsFullyReadEvent(evt.Metadata) {
+				continue
+			}
+
+			frEvt, err := AsFullyReadEvent(evt)
+			if err != nil {
+				return Unread{}, err
+			}
+
+			marker = frEvt.Content.ID
+		}
+	}
+
+	unread := Unread{
+		Threads:      map[string][]event.Opaque{},
+		Counts:       room.UnreadNotificationsCounts,
+		ThreadCounts: room.UnreadThreadNotifications,
+	}
+
+	events := room.Timeline.Events
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if marker != "" && events[i].ID == marker {
+			unread.MarkerFound = true
+			events = events[i+1:]
+
+			break
+		}
+	}
+
+	for _, evt := range events {
+		if evt.Sender == user {
+			continue
+		}
+
+		var relation threadRelation
+
+		// Content that does not fit the relation schema simply is not part of a thread.
+		_ = json.Unmarshal(evt.Content, &relation)
+
+		if relation.RelatesTo.Type == "m.thread" && relation.RelatesTo.ID != "" {
+			unread.Threads[relation.RelatesTo.ID] = append(unread.Threads[relation.RelatesTo.ID], evt)
+
+			continue
+		}
+
+		unread.Events = append(unread.Events, evt)
+	}
+
+	return unread, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"eqrx.net/matrix"
+	"eqrx.net/matrix/event"
+)
+
+// MembersQuery selects member events of a room. All fields are optional.
+type MembersQuery struct {
+	// At is a sync token. Members are returned as they were at that point.
+	At string
+	// Membership only selects members with this membership.
+	Membership string
+	// NotMembership excludes members with this membership.
+	NotMembership string
+}
+
+// Members returns the member events of the given room ID or alias that are selected by the given query.
+func Members(
+	ctx context.Context, cli matrix.Client, id ID, query MembersQuery,
+) ([]StateEvent[MemberContent], error) {
+	path, err := roomPath(ctx, cli, id, "members")
+	if err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+
+	for key, value := range map[string]string{
+		"at": query.At, "membership": query.Membership, "not_membership": query.NotMembership,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	if len(values) != 0 {
+		path += "?" + values.Encode()
+	}
+
+	var response struct {
+		matrix.Response
+		Chunk []event.Opaque `json:"chunk"`
+	}
+
+	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return nil, fmt.Errorf("list members: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return nil, err
+	}
+
+	members := make([]StateEvent[MemberContent], 0, len(response.Chunk))
+
+	for _, evt := range response.Chunk {
+		member, err := AsStateEvent[MemberContent](evt)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// JoinedMember is the profile of a joined member of a room.
+type JoinedMember struct {
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+// JoinedMembers returns the profiles of the joined members of the given room ID or alias mapped by user ID.
+func JoinedMembers(ctx context.Context, cli matrix.Client, id ID) (map[string]JoinedMember, error) {
+	path, err := roomPath(ctx, cli, id, "joined_members")
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		matrix.Response
+		Joined map[string]JoinedMember `json:"joined"`
+	}
+
+	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return nil, fmt.Errorf("list joined members: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return nil, err
+	}
+
+	return response.Joined, nil
+}
+
+// UsersByDisplayName returns the sorted user IDs of the given members whose display name equals the given name,
+// ignoring case. Display names are not unique, so more than one user may be returned.
+func UsersByDisplayName(members map[string]JoinedMember, name string) []string {
+	var users []string
+
+	for user, member := range members {
+		if strings.EqualFold(member.DisplayName, name) {
+			users = append(users, user)
+		}
+	}
+
+	sort.Strings(users)
+
+	return users
+}
@@ -0,0 +1,201 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"eqrx.net/matrix"
+	"eqrx.net/matrix/sync"
+)
+
+type reasonRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type userRequest struct {
+	User   string `json:"user_id"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func viaQuery(via []string) string {
+	if len(via) == 0 {
+		return ""
+	}
+
+	query := url.Values{"server_name": via}
+
+	return "?" + query.Encode()
+}
+
+func changeMembership(ctx context.Context, cli matrix.Client, id ID, action string, request interface{}) error {
+	path, err := roomPath(ctx, cli, id, action)
+	if err != nil {
+		return err
+	}
+
+	var response matrix.Response
+
+	if err := cli.HTTP(ctx, http.MethodPost, path, request, &response); err != nil {
+		return fmt.Errorf("%s room: %w", action, err)
+	}
+
+	return response.AsError()
+}
+
+func changeUserMembership(ctx context.Context, cli matrix.Client, id ID, action, user, reason string) error {
+	if user == "" {
+		panic("user id empty")
+	}
+
+	return changeMembership(ctx, cli, id, action, userRequest{user, reason})
+}
+
+// Leave the given room ID or alias with the client. The reason may be empty.
+func Leave(ctx context.Context, cli matrix.Client, id ID, reason string) error {
+	return changeMembership(ctx, cli, id, "leave", reasonRequest{reason})
+}
+
+// Forget the given room ID or alias the client has left so it is not returned by the server anymore.
+func Forget(ctx context.Context, cli matrix.Client, id ID) error {
+	return changeMembership(ctx, cli, id, "forget", struct{}{})
+}
+
+// Invite the given user ID to the given room ID or alias with the client. The reason may be empty.
+func Invite(ctx context.Context, cli matrix.Client, id ID, user, reason string) error {
+	return changeUserMembership(ctx, cli, id, "invite", user, reason)
+}
+
+// Kick the given user ID from the given room ID or alias with the client. The reason may be empty.
+func Kick(ctx context.Context, cli matrix.Client, id ID, user, reason string) error {
+	return changeUserMembership(ctx, cli, id, "kick", user, reason)
+}
+
+// Ban the given user ID from the given room ID or alias with the client. The reason may be empty.
+func Ban(ctx context.Context, cli matrix.Client, id ID, user, reason string) error {
+	return changeUserMembership(ctx, cli, id, "ban", user, reason)
+}
+
+// Unban the given user ID from the given room ID or alias with the client. The reason may be empty.
+func Unban(ctx context.Context, cli matrix.Client, id ID, user, reason string) error {
+	return changeUserMembership(ctx, cli, id, "unban", user, reason)
+}
+
+// Knock on the given room ID or alias with the client to ask for an invite. The reason may be empty. Via are server
+// names to knock through. Returns the ID of the room.
+func Knock(ctx context.Context, cli matrix.Client, id ID, reason string, via ...string) (ID, error) {
+	if id == "" {
+		panic("room id empty")
+	}
+
+	path := "/_matrix/client/v3/knock/" + url.PathEscape(string(id)) + viaQuery(via)
+
+	var response struct {
+		matrix.Response
+		ID ID `json:"room_id"`
+	}
+
+	if err := cli.HTTP(ctx, http.MethodPost, path, reasonRequest{reason}, &response); err != nil {
+		return "", fmt.Errorf("knock room: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return "", err
+	}
+
+	return response.ID, nil
+}
+
+// InvitePolicy decides if an invite of the given inviter user ID to the given room ID is accepted.
+type InvitePolicy func(room ID, inviter string) bool
+
+// AllowInviters returns an InvitePolicy that accepts invites from the given user IDs. Entries that do not start
+// with @ are taken as server names and allow all users of that server.
+func AllowInviters(entries ...string) InvitePolicy {
+	users := map[string]struct{}{}
+	servers := map[string]struct{}{}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry, "@") {
+			users[entry] = struct{}{}
+		} else {
+			servers[entry] = struct{}{}
+		}
+	}
+
+	return func(_ ID, inviter string) bool {
+		if _, ok := users[inviter]; ok {
+			return true
+		}
+
+		_, ok := servers[ServerName(inviter)]
+
+		return ok
+	}
+}
+
+// ServerName returns the server name part of the given user, room or event ID or alias.
+func ServerName(id string) string {
+	if idx := strings.IndexByte(id, ':'); idx >= 0 {
+		return id[idx+1:]
+	}
+
+	return ""
+}
+
+// Inviter returns the user ID of the user that invited the given user to the given invited room. Returns an empty
+// string if the invite state does not contain the invite.
+func Inviter(room sync.InvitedRoom, user string) string {
+	for _, evt := range room.State.Events {
+		if evt.Type != EventTypeMember || evt.StateKey == nil || *evt.StateKey != user {
+			continue
+		}
+
+		member, err := AsStateEvent[MemberContent](evt)
+		if err == nil && member.Content.Membership == MembershipInvite {
+			return evt.Sender
+		}
+	}
+
+	return ""
+}
+
+// AcceptInvites joins all invited rooms of the given sync rooms whose invite is accepted by the given policy with the
+// client. The server of the inviter is used to join through. Returns the IDs of the joined rooms. Stops at the first
+// join that fails and returns the rooms joined until then.
+func AcceptInvites(ctx context.Context, cli matrix.Client, rooms sync.Rooms, policy InvitePolicy) ([]ID, error) {
+	joined := make([]ID, 0, len(rooms.Invited))
+
+	for key, room := range rooms.Invited {
+		id := ID(key)
+
+		inviter := Inviter(room, cli.User())
+		if inviter == "" || !policy(id, inviter) {
+			continue
+		}
+
+		joinedID, err := Join(ctx, cli, id, "", ServerName(inviter))
+		if err != nil {
+			return joined, err
+		}
+
+		joined = append(joined, joinedID)
+	}
+
+	return joined, nil
+}
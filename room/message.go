@@ -15,7 +15,6 @@ package room
 
 import (
 	"context"
-	"encoding/json"
 
 	"eqrx.net/matrix"
 	"eqrx.net/matrix/event"
@@ -36,7 +35,7 @@ func IsMessageEvent(evt event.Metadata) bool {
 // MessageEvent is a message sent to a room.
 type MessageEvent struct {
 	event.Metadata
-	Content MessageContent `struct:"content"`
+	Content MessageContent `json:"content"`
 }
 
 // MessageContent represents the content of room message.
@@ -64,20 +63,12 @@ func NewTextMessage(room, body string) MessageEvent {
 	return MessageEvent{event.Metadata{Room: room}, MessageContent{"m.text", body, nil}}
 }
 
-// AsMessageEvent converts the given opaque event to a room message. Panics is metadata indicates that the event is not
-// a room message and returns an error if unmarshalling of the content failed.
+// AsMessageEvent converts the given opaque event to a room message. Returns an error if metadata indicates that the
+// event is not a room message or if unmarshalling of the content failed.
 func AsMessageEvent(evt event.Opaque) (MessageEvent, error) {
-	if evt.Type != EventTypeMessage {
-		panic("not message")
-	}
+	content, err := event.As[MessageContent](evt)
 
-	mevt := MessageEvent{Metadata: evt.Metadata}
-
-	if err := json.Unmarshal(evt.Content, &mevt.Content); err != nil {
-		return mevt, nil
-	}
-
-	return mevt, nil
+	return MessageEvent{evt.Metadata, content}, err
 }
 
 // AsReplyTo marks the message as a reply to the given event ID.
@@ -0,0 +1,177 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+// Default levels used for fields that are missing in a power levels event.
+const (
+	DefaultBanLevel          = 50
+	DefaultKickLevel         = 50
+	DefaultRedactLevel       = 50
+	DefaultInviteLevel       = 0
+	DefaultStateLevel        = 50
+	DefaultEventsLevel       = 0
+	DefaultUsersLevel        = 0
+	DefaultNotificationLevel = 50
+)
+
+// NotificationRoom is the notification key that is required to notify the whole room with @room.
+const NotificationRoom = "room"
+
+func levelOr(level *int, def int) int {
+	if level == nil {
+		return def
+	}
+
+	return *level
+}
+
+// BanLevel returns the level required to ban users.
+func (p PowerLevelsContent) BanLevel() int { return levelOr(p.Ban, DefaultBanLevel) }
+
+// KickLevel returns the level required to kick users.
+func (p PowerLevelsContent) KickLevel() int { return levelOr(p.Kick, DefaultKickLevel) }
+
+// RedactLevel returns the level required to redact events of other users.
+func (p PowerLevelsContent) RedactLevel() int { return levelOr(p.Redact, DefaultRedactLevel) }
+
+// InviteLevel returns the level required to invite users.
+func (p PowerLevelsContent) InviteLevel() int { return levelOr(p.Invite, DefaultInviteLevel) }
+
+// UserLevel returns the level of the given user ID.
+func (p PowerLevelsContent) UserLevel(user string) int {
+	if level, ok := p.Users[user]; ok {
+		return level
+	}
+
+	return levelOr(p.UsersDefault, DefaultUsersLevel)
+}
+
+// EventLevel returns the level required to send events of the given type. isState selects the default for state
+// events if the type has no explicit level.
+func (p PowerLevelsContent) EventLevel(eventType string, isState bool) int {
+	if level, ok := p.Events[eventType]; ok {
+		return level
+	}
+
+	if isState {
+		return levelOr(p.StateDefault, DefaultStateLevel)
+	}
+
+	return levelOr(p.EventsDefault, DefaultEventsLevel)
+}
+
+// NotificationLevel returns the level required to trigger the notification with the given key like NotificationRoom.
+func (p PowerLevelsContent) NotificationLevel(key string) int {
+	if level, ok := p.Notifications[key]; ok {
+		return level
+	}
+
+	return DefaultNotificationLevel
+}
+
+// CanSend returns if the given user may send events of the given type.
+func (p PowerLevelsContent) CanSend(user, eventType string, isState bool) bool {
+	return p.UserLevel(user) >= p.EventLevel(eventType, isState)
+}
+
+// CanInvite returns if the given user may invite other users.
+func (p PowerLevelsContent) CanInvite(user string) bool {
+	return p.UserLevel(user) >= p.InviteLevel()
+}
+
+// CanKick returns if the actor may kick the target. The actor needs the kick level and a higher level than the target.
+func (p PowerLevelsContent) CanKick(actor, target string) bool {
+	level := p.UserLevel(actor)
+
+	return level >= p.KickLevel() && level > p.UserLevel(target)
+}
+
+// CanBan returns if the actor may ban or unban the target. The actor needs the ban level and a higher level than
+// the target.
+func (p PowerLevelsContent) CanBan(actor, target string) bool {
+	level := p.UserLevel(actor)
+
+	return level >= p.BanLevel() && level > p.UserLevel(target)
+}
+
+// CanRedact returns if the actor may redact an event sent by the given sender. Users may always redact their own
+// events as long as they may send redactions.
+func (p PowerLevelsContent) CanRedact(actor, sender string) bool {
+	if !p.CanSend(actor, EventTypeRedaction, false) {
+		return false
+	}
+
+	return actor == sender || p.UserLevel(actor) >= p.RedactLevel()
+}
+
+// CanNotify returns if the given user may trigger the notification with the given key like NotificationRoom.
+func (p PowerLevelsContent) CanNotify(user, key string) bool {
+	return p.UserLevel(user) >= p.NotificationLevel(key)
+}
+
+// CanSetUserLevel returns if the actor may change the level of the target to the given level. The actor needs to
+// be allowed to send power levels, may not grant more than its own level and may only change the level of users
+// below it or its own.
+func (p PowerLevelsContent) CanSetUserLevel(actor, target string, level int) bool {
+	actorLevel := p.UserLevel(actor)
+
+	if !p.CanSend(actor, EventTypePowerLevels, true) || level > actorLevel {
+		return false
+	}
+
+	return actor == target || p.UserLevel(target) < actorLevel
+}
+
+func copyLevels(levels map[string]int) map[string]int {
+	copied := make(map[string]int, len(levels)+1)
+	for key, level := range levels {
+		copied[key] = level
+	}
+
+	return copied
+}
+
+// WithUserLevel returns a copy of the power levels with the level of the given user set. The receiver is not
+// modified, so the result can be sent with SetPowerLevels while the original is kept.
+func (p PowerLevelsContent) WithUserLevel(user string, level int) PowerLevelsContent {
+	p.Users = copyLevels(p.Users)
+	p.Users[user] = level
+
+	return p
+}
+
+// WithoutUser returns a copy of the power levels without an explicit level for the given user. The user falls back
+// to the users default level.
+func (p PowerLevelsContent) WithoutUser(user string) PowerLevelsContent {
+	p.Users = copyLevels(p.Users)
+	delete(p.Users, user)
+
+	return p
+}
+
+// WithEventLevel returns a copy of the power levels with the level required to send the given event type set.
+func (p PowerLevelsContent) WithEventLevel(eventType string, level int) PowerLevelsContent {
+	p.Events = copyLevels(p.Events)
+	p.Events[eventType] = level
+
+	return p
+}
+
+// WithNotificationLevel returns a copy of the power levels with the level required for the given notification key set.
+func (p PowerLevelsContent) WithNotificationLevel(key string, level int) PowerLevelsContent {
+	p.Notifications = copyLevels(p.Notifications)
+	p.Notifications[key] = level
+
+	return p
+}
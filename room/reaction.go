@@ -15,7 +15,6 @@ package room
 
 import (
 	"context"
-	"encoding/json"
 
 	"eqrx.net/matrix"
 	"eqrx.net/matrix/event"
@@ -32,7 +31,7 @@ func IsReactionEvent(evt event.Metadata) bool {
 // ReactionEvent is a reaction sent to a room.
 type ReactionEvent struct {
 	event.Metadata
-	Content ReactionContent `struct:"content"`
+	Content ReactionContent `json:"content"`
 }
 
 // ReactionContent represents the content of room reaction.
@@ -53,20 +52,12 @@ func NewReaction(room, key, toID string) ReactionEvent {
 	}
 }
 
-// AsReactionEvent converts the given opaque event to a room reaction. Panics is metadata indicates that the event is
-// not a room message and returns an error if unmarshalling of the content failed.
+// AsReactionEvent converts the given opaque event to a room reaction. Returns an error if metadata indicates that the
+// event is not a room reaction or if unmarshalling of the content failed.
 func AsReactionEvent(evt event.Opaque) (ReactionEvent, error) {
-	if evt.Type != EventTypeReaction {
-		panic("not reaction")
-	}
-
-	rEvt := ReactionEvent{Metadata: evt.Metadata}
-
-	if err := json.Unmarshal(evt.Content, &rEvt.Content); err != nil {
-		return rEvt, nil
-	}
+	content, err := event.As[ReactionContent](evt)
 
-	return rEvt, nil
+	return ReactionEvent{evt.Metadata, content}, err
 }
 
 // Send the event via the given matrix client with the given transaction ID.
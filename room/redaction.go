@@ -0,0 +1,145 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"eqrx.net/matrix"
+	"eqrx.net/matrix/event"
+)
+
+// EventTypeRedaction in a event type field indicates that the event is a redaction of another event.
+const EventTypeRedaction = "m.room.redaction"
+
+// IsRedactionEvent returns true if the given event metadata indicates that the event is a redaction.
+func IsRedactionEvent(evt event.Metadata) bool {
+	return evt.Type == EventTypeRedaction
+}
+
+// RedactionEvent is a redaction of another event in a room.
+type RedactionEvent struct {
+	event.Metadata
+	Content RedactionContent `json:"content"`
+	// Redacts is the ID of the redacted event, regardless if it was given as part of the content (room version 11
+	// and later) or as top level field (all earlier room versions).
+	Redacts string `json:"-"`
+	// Source is the event the redaction was converted from. Apply stores it unchanged in redacted events.
+	Source event.Opaque `json:"-"`
+}
+
+// ErrRedactionMismatch is returned when applying a redaction to an event it does not redact.
+var ErrRedactionMismatch = errors.New("redaction does not redact event")
+
+// RedactionContent represents the content of a redaction.
+type RedactionContent struct {
+	Redacts string `json:"redacts,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// AsRedactionEvent converts the given opaque event to a redaction. Returns an error if metadata indicates that the
+// event is not a redaction or if unmarshalling of the content failed.
+func AsRedactionEvent(evt event.Opaque) (RedactionEvent, error) {
+	content, err := event.As[RedactionContent](evt)
+	rEvt := RedactionEvent{evt.Metadata, content, content.Redacts, evt}
+
+	if err != nil {
+		return rEvt, err
+	}
+
+	if raw, ok := evt.Extra["redacts"]; ok && rEvt.Redacts == "" {
+		if err := json.Unmarshal(raw, &rEvt.Redacts); err != nil {
+			return rEvt, fmt.Errorf("unmarshal redacts: %w", err)
+		}
+	}
+
+	return rEvt, nil
+}
+
+// Apply the redaction to the given event of a room with the given version. Returns the redacted form of the event
+// with the redaction stored in its unsigned data. Returns an error wrapping ErrRedactionMismatch if the redaction
+// does not redact the given event.
+func (r RedactionEvent) Apply(evt event.Opaque, roomVersion string) (event.Opaque, error) {
+	if evt.ID == "" || evt.ID != r.Redacts {
+		return evt, fmt.Errorf("%w: %s redacts %q, not %q", ErrRedactionMismatch, r.ID, r.Redacts, evt.ID)
+	}
+
+	redacted, err := event.Redact(evt, roomVersion)
+	if err != nil {
+		return redacted, err
+	}
+
+	because := r.Source
+	if because.Type == "" {
+		// The redaction was not converted from an event, build one from its fields.
+		if because, err = r.opaque(); err != nil {
+			return redacted, err
+		}
+	}
+
+	redacted.Unsigned = &event.UnsignedData{RedactedBecause: &because}
+
+	return redacted, nil
+}
+
+// opaque builds an opaque event from the fields of the redaction.
+func (r RedactionEvent) opaque() (event.Opaque, error) {
+	content, err := json.Marshal(r.Content)
+	if err != nil {
+		return event.Opaque{}, fmt.Errorf("marshal redaction content: %w", err)
+	}
+
+	evt := event.Opaque{Metadata: r.Metadata, Content: content}
+	if r.Redacts != r.Content.Redacts {
+		evt.Extra = map[string]json.RawMessage{}
+		if evt.Extra["redacts"], err = json.Marshal(r.Redacts); err != nil {
+			return event.Opaque{}, fmt.Errorf("marshal redacts: %w", err)
+		}
+	}
+
+	return evt, nil
+}
+
+type redactRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// Redact the event with the given ID in the given room ID or alias with the given client. The reason may be empty.
+// Returns the ID of the redaction event.
+func Redact(ctx context.Context, cli matrix.Client, id ID, eventID, reason string) (string, error) {
+	if eventID == "" {
+		panic("event id empty")
+	}
+
+	path, err := roomPath(ctx, cli, id, "redact", eventID, cli.NextTXID())
+	if err != nil {
+		return "", err
+	}
+
+	var response sendResponse
+
+	if err := cli.HTTP(ctx, http.MethodPut, path, redactRequest{reason}, &response); err != nil {
+		return "", fmt.Errorf("redact event: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return "", err
+	}
+
+	return response.ID, nil
+}
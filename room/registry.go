@@ -0,0 +1,42 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import "eqrx.net/matrix/event"
+
+// init registers the content types of all event types of this package, so events of a sync response can be decoded
+// with event.As and AsStateEvent without further setup.
+//
+//nolint:gochecknoinits // Registration must happen before the first event is decoded, which may be in any package.
+func init() {
+	event.Register[CreateContent](EventTypeCreate)
+	event.Register[NameContent](EventTypeName)
+	event.Register[TopicContent](EventTypeTopic)
+	event.Register[AvatarContent](EventTypeAvatar)
+	event.Register[MemberContent](EventTypeMember)
+	event.Register[PowerLevelsContent](EventTypePowerLevels)
+	event.Register[JoinRulesContent](EventTypeJoinRules)
+	event.Register[HistoryVisibilityContent](EventTypeHistoryVisibility)
+	event.Register[GuestAccessContent](EventTypeGuestAccess)
+	event.Register[CanonicalAliasContent](EventTypeCanonicalAlias)
+	event.Register[EncryptionContent](EventTypeEncryption)
+	event.Register[TombstoneContent](EventTypeTombstone)
+	event.Register[PinnedEventsContent](EventTypePinnedEvents)
+	event.Register[ServerACLContent](EventTypeServerACL)
+	event.Register[MessageContent](EventTypeMessage)
+	event.Register[ReactionContent](EventTypeReaction)
+	event.Register[FullyReadContent](EventTypeFullyRead)
+	event.Register[TagContent](EventTypeTag)
+	event.Register[RedactionContent](EventTypeRedaction)
+}
@@ -18,34 +18,43 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"eqrx.net/matrix"
 )
 
-// Join the given room ID with the client.
-func Join(ctx context.Context, cli matrix.Client, id string) error {
-	path := "/_matrix/client/v3/join/" + id
+// Join the given room ID or alias with the client. The reason may be empty. Via are server names to join the room
+// through if the server of the client is not part of the room yet. Returns the ID of the joined room.
+func Join(ctx context.Context, cli matrix.Client, id ID, reason string, via ...string) (ID, error) {
+	if id == "" {
+		panic("room id empty")
+	}
+
+	path := "/_matrix/client/v3/join/" + url.PathEscape(string(id)) + viaQuery(via)
 
-	var joinRoomResponse matrix.Response
+	var joinRoomResponse struct {
+		matrix.Response
+		ID ID `json:"room_id"`
+	}
 
-	if err := cli.HTTP(ctx, http.MethodPost, path, nil, &joinRoomResponse); err != nil {
-		return fmt.Errorf("join rooms: %w", err)
+	if err := cli.HTTP(ctx, http.MethodPost, path, reasonRequest{reason}, &joinRoomResponse); err != nil {
+		return "", fmt.Errorf("join rooms: %w", err)
 	}
 
 	if err := joinRoomResponse.AsError(); err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return joinRoomResponse.ID, nil
 }
 
 // Joined returns all rooms this client is part of.
-func Joined(ctx context.Context, cli matrix.Client) ([]string, error) {
+func Joined(ctx context.Context, cli matrix.Client) ([]ID, error) {
 	path := "/_matrix/client/v3/joined_rooms"
 
 	var listRoomsResponse struct {
 		matrix.Response
-		Rooms []string `json:"joined_rooms"`
+		Rooms []ID `json:"joined_rooms"`
 	}
 
 	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &listRoomsResponse); err != nil {
@@ -32,7 +32,10 @@ func sendContent(
 		panic("parameter empty")
 	}
 
-	path := "/_matrix/client/v3/rooms/" + roomID + "/send/" + eventType + "/" + cli.NextTXID()
+	path, err := roomPath(ctx, cli, ID(roomID), "send", eventType, cli.NextTXID())
+	if err != nil {
+		return "", err
+	}
 
 	var response sendResponse
 
@@ -0,0 +1,366 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"eqrx.net/matrix/event"
+)
+
+// Event types of room state events. Unless noted otherwise the state key of these events is empty.
+const (
+	// EventTypeCreate is the first event of a room and describes how it was created.
+	EventTypeCreate = "m.room.create"
+	// EventTypeName sets the name of a room.
+	EventTypeName = "m.room.name"
+	// EventTypeTopic sets the topic of a room.
+	EventTypeTopic = "m.room.topic"
+	// EventTypeAvatar sets the avatar of a room.
+	EventTypeAvatar = "m.room.avatar"
+	// EventTypeMember sets the membership of a user. The state key is the user ID of that user.
+	EventTypeMember = "m.room.member"
+	// EventTypePowerLevels sets the power levels of the room.
+	EventTypePowerLevels = "m.room.power_levels"
+	// EventTypeJoinRules sets who may join the room.
+	EventTypeJoinRules = "m.room.join_rules"
+	// EventTypeHistoryVisibility sets who may see the history of the room.
+	EventTypeHistoryVisibility = "m.room.history_visibility"
+	// EventTypeGuestAccess sets if guests may join the room.
+	EventTypeGuestAccess = "m.room.guest_access"
+	// EventTypeCanonicalAlias sets the aliases the room is advertised with.
+	EventTypeCanonicalAlias = "m.room.canonical_alias"
+	// EventTypeEncryption enables end-to-end encryption in the room.
+	EventTypeEncryption = "m.room.encryption"
+	// EventTypeTombstone indicates that the room has been replaced by another room.
+	EventTypeTombstone = "m.room.tombstone"
+	// EventTypePinnedEvents sets the events pinned in the room.
+	EventTypePinnedEvents = "m.room.pinned_events"
+	// EventTypeServerACL sets which servers may participate in the room.
+	EventTypeServerACL = "m.room.server_acl"
+)
+
+// CreateContent represents the content of a room creation event.
+type CreateContent struct {
+	Creator     string       `json:"creator,omitempty"`
+	Federate    *bool        `json:"m.federate,omitempty"`
+	Version     string       `json:"room_version,omitempty"`
+	Type        string       `json:"type,omitempty"`
+	Predecessor *Predecessor `json:"predecessor,omitempty"`
+}
+
+// Predecessor references the room that was replaced by a room and the tombstone event of it.
+type Predecessor struct {
+	Room  ID     `json:"room_id"`
+	Event string `json:"event_id"`
+}
+
+// NameContent represents the content of a room name event.
+type NameContent struct {
+	Name string `json:"name"`
+}
+
+// TopicContent represents the content of a room topic event.
+type TopicContent struct {
+	Topic string `json:"topic"`
+}
+
+// AvatarContent represents the content of a room avatar event.
+type AvatarContent struct {
+	URL  string     `json:"url,omitempty"`
+	Info *ImageInfo `json:"info,omitempty"`
+}
+
+// ImageInfo describes an image.
+type ImageInfo struct {
+	Height   int    `json:"h,omitempty"`
+	Width    int    `json:"w,omitempty"`
+	MimeType string `json:"mimetype,omitempty"`
+	Size     int    `json:"size,omitempty"`
+}
+
+// Memberships a user may have in a room.
+const (
+	MembershipInvite = "invite"
+	MembershipJoin   = "join"
+	MembershipKnock  = "knock"
+	MembershipLeave  = "leave"
+	MembershipBan    = "ban"
+)
+
+// MemberContent represents the content of a room member event.
+type MemberContent struct {
+	Membership       string            `json:"membership"`
+	DisplayName      *string           `json:"displayname,omitempty"`
+	AvatarURL        string            `json:"avatar_url,omitempty"`
+	IsDirect         bool              `json:"is_direct,omitempty"`
+	Reason           string            `json:"reason,omitempty"`
+	AuthorisedVia    string            `json:"join_authorised_via_users_server,omitempty"`
+	ThirdPartyInvite *ThirdPartyInvite `json:"third_party_invite,omitempty"`
+}
+
+// ThirdPartyInvite of a member event that was created for an invite to a third party identifier.
+type ThirdPartyInvite struct {
+	DisplayName string          `json:"display_name"`
+	Signed      json.RawMessage `json:"signed"`
+}
+
+// PowerLevelsContent represents the content of a room power levels event. Levels that are nil are not set in the
+// event and take their default value. Levels encoded as strings, as allowed before room version 10, are decoded
+// as integers.
+type PowerLevelsContent struct {
+	Ban           *int           `json:"ban,omitempty"`
+	Events        map[string]int `json:"events,omitempty"`
+	EventsDefault *int           `json:"events_default,omitempty"`
+	Invite        *int           `json:"invite,omitempty"`
+	Kick          *int           `json:"kick,omitempty"`
+	Notifications map[string]int `json:"notifications,omitempty"`
+	Redact        *int           `json:"redact,omitempty"`
+	StateDefault  *int           `json:"state_default,omitempty"`
+	Users         map[string]int `json:"users,omitempty"`
+	UsersDefault  *int           `json:"users_default,omitempty"`
+}
+
+// level is a power level when decoding. Room versions before 10 allow levels to be encoded as strings containing
+// an integer.
+type level int
+
+// UnmarshalJSON accepts integers and strings containing an integer.
+func (l *level) UnmarshalJSON(b []byte) error {
+	var number int
+	if err := json.Unmarshal(b, &number); err == nil {
+		*l = level(number)
+
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return fmt.Errorf("power level %s is neither integer nor string", b)
+	}
+
+	number, err := strconv.Atoi(strings.TrimSpace(str))
+	if err != nil {
+		return fmt.Errorf("power level %q: %w", str, err)
+	}
+
+	*l = level(number)
+
+	return nil
+}
+
+func (l *level) int() *int {
+	if l == nil {
+		return nil
+	}
+
+	value := int(*l)
+
+	return &value
+}
+
+func levelMap(levels map[string]level) map[string]int {
+	if levels == nil {
+		return nil
+	}
+
+	converted := make(map[string]int, len(levels))
+	for key, value := range levels {
+		converted[key] = int(value)
+	}
+
+	return converted
+}
+
+// UnmarshalJSON decodes power levels that are encoded as integers or strings.
+func (p *PowerLevelsContent) UnmarshalJSON(b []byte) error {
+	var levels struct {
+		Ban           *level           `json:"ban"`
+		Events        map[string]level `json:"events"`
+		EventsDefault *level           `json:"events_default"`
+		Invite        *level           `json:"invite"`
+		Kick          *level           `json:"kick"`
+		Notifications map[string]level `json:"notifications"`
+		Redact        *level           `json:"redact"`
+		StateDefault  *level           `json:"state_default"`
+		Users         map[string]level `json:"users"`
+		UsersDefault  *level           `json:"users_default"`
+	}
+
+	if err := json.Unmarshal(b, &levels); err != nil {
+		return fmt.Errorf("unmarshal power levels: %w", err)
+	}
+
+	*p = PowerLevelsContent{
+		Ban:           levels.Ban.int(),
+		Events:        levelMap(levels.Events),
+		EventsDefault: levels.EventsDefault.int(),
+		Invite:        levels.Invite.int(),
+		Kick:          levels.Kick.int(),
+		Notifications: levelMap(levels.Notifications),
+		Redact:        levels.Redact.int(),
+		StateDefault:  levels.StateDefault.int(),
+		Users:         levelMap(levels.Users),
+		UsersDefault:  levels.UsersDefault.int(),
+	}
+
+	return nil
+}
+
+// Join rules of a room.
+const (
+	JoinRulePublic     = "public"
+	JoinRuleKnock      = "knock"
+	JoinRuleInvite     = "invite"
+	JoinRulePrivate    = "private"
+	JoinRuleRestricted = "restricted"
+)
+
+// JoinRulesContent represents the content of a room join rules event.
+type JoinRulesContent struct {
+	JoinRule string           `json:"join_rule"`
+	Allow    []AllowCondition `json:"allow,omitempty"`
+}
+
+// AllowCondition allows users to join a restricted room if they are member of another room.
+type AllowCondition struct {
+	Type string `json:"type"`
+	Room ID     `json:"room_id,omitempty"`
+}
+
+// History visibilities of a room.
+const (
+	HistoryVisibilityInvited       = "invited"
+	HistoryVisibilityJoined        = "joined"
+	HistoryVisibilityShared        = "shared"
+	HistoryVisibilityWorldReadable = "world_readable"
+)
+
+// HistoryVisibilityContent represents the content of a room history visibility event.
+type HistoryVisibilityContent struct {
+	HistoryVisibility string `json:"history_visibility"`
+}
+
+// Guest access settings of a room.
+const (
+	GuestAccessCanJoin   = "can_join"
+	GuestAccessForbidden = "forbidden"
+)
+
+// GuestAccessContent represents the content of a room guest access event.
+type GuestAccessContent struct {
+	GuestAccess string `json:"guest_access"`
+}
+
+// CanonicalAliasContent represents the content of a room canonical alias event.
+type CanonicalAliasContent struct {
+	Alias      string   `json:"alias,omitempty"`
+	AltAliases []string `json:"alt_aliases,omitempty"`
+}
+
+// EncryptionContent represents the content of a room encryption event.
+type EncryptionContent struct {
+	Algorithm              string `json:"algorithm"`
+	RotationPeriodMilliSec int    `json:"rotation_period_ms,omitempty"`
+	RotationPeriodMessages int    `json:"rotation_period_msgs,omitempty"`
+}
+
+// TombstoneContent represents the content of a room tombstone event.
+type TombstoneContent struct {
+	Body            string `json:"body"`
+	ReplacementRoom ID     `json:"replacement_room"`
+}
+
+// PinnedEventsContent represents the content of a room pinned events event.
+type PinnedEventsContent struct {
+	Pinned []string `json:"pinned"`
+}
+
+// ServerACLContent represents the content of a room server ACL event. Allow and Deny contain server names
+// that may use * and ? as wildcards.
+type ServerACLContent struct {
+	AllowIPLiterals *bool    `json:"allow_ip_literals,omitempty"`
+	Allow           []string `json:"allow"`
+	Deny            []string `json:"deny"`
+}
+
+// StateEvent is a room state event with content of type T. PreviousContent is set if the event replaced a previous
+// state event and the server included its content. It is not marshalled itself since the unsigned data of the
+// metadata still contains it as prev_content.
+type StateEvent[T any] struct {
+	event.Metadata
+	Content         T  `json:"content"`
+	PreviousContent *T `json:"-"`
+}
+
+// ErrNotState is returned when decoding an event as state event that is not one.
+var ErrNotState = errors.New("not a state event")
+
+// AsStateEvent converts the given opaque event to a state event with content of type T. Returns an error if T is not
+// registered for the event type, the event is not a state event, the state key does not fit the event type or
+// unmarshalling of the content failed.
+func AsStateEvent[T any](evt event.Opaque) (StateEvent[T], error) {
+	sEvt := StateEvent[T]{Metadata: evt.Metadata}
+
+	if err := checkStateKey(evt.Metadata); err != nil {
+		return sEvt, err
+	}
+
+	content, err := event.As[T](evt)
+	if err != nil {
+		return sEvt, err
+	}
+
+	sEvt.Content = content
+
+	if evt.Unsigned != nil && len(evt.Unsigned.PreviousContent) != 0 {
+		previous := evt
+		previous.Content = evt.Unsigned.PreviousContent
+
+		prevContent, err := event.As[T](previous)
+		if err != nil {
+			return sEvt, fmt.Errorf("previous content: %w", err)
+		}
+
+		sEvt.PreviousContent = &prevContent
+	}
+
+	return sEvt, nil
+}
+
+// checkStateKey ensures that the metadata belongs to a state event with a state key that fits its type.
+func checkStateKey(evt event.Metadata) error {
+	if !evt.IsState() {
+		return fmt.Errorf("%w: %s", ErrNotState, evt.Type)
+	}
+
+	switch evt.Type {
+	case EventTypeMember:
+		if !strings.HasPrefix(*evt.StateKey, "@") {
+			return fmt.Errorf("%w: %s with state key %q that is no user ID", ErrNotState, evt.Type, *evt.StateKey)
+		}
+	case EventTypeCreate, EventTypeName, EventTypeTopic, EventTypeAvatar, EventTypePowerLevels, EventTypeJoinRules,
+		EventTypeHistoryVisibility, EventTypeGuestAccess, EventTypeCanonicalAlias, EventTypeEncryption,
+		EventTypeTombstone, EventTypePinnedEvents, EventTypeServerACL:
+		if *evt.StateKey != "" {
+			return fmt.Errorf("%w: %s with non empty state key %q", ErrNotState, evt.Type, *evt.StateKey)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"eqrx.net/matrix"
+	"eqrx.net/matrix/event"
+)
+
+// State returns all current state events of the given room ID or alias.
+func State(ctx context.Context, cli matrix.Client, id ID) ([]event.Opaque, error) {
+	path, err := roomPath(ctx, cli, id, "state")
+	if err != nil {
+		return nil, err
+	}
+
+	var events []event.Opaque
+
+	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &events); err != nil {
+		return nil, fmt.Errorf("get room state: %w", err)
+	}
+
+	return events, nil
+}
+
+// GetState returns the content of the current state event with the given type and state key of the given room ID or
+// alias, decoded into T. Returns an error matching matrix.IsErrorCode(err, "M_NOT_FOUND") if there is none.
+func GetState[T any](ctx context.Context, cli matrix.Client, id ID, eventType, stateKey string) (T, error) {
+	var content T
+
+	if eventType == "" {
+		panic("event type empty")
+	}
+
+	path, err := roomPath(ctx, cli, id, "state", eventType, stateKey)
+	if err != nil {
+		return content, err
+	}
+
+	var response json.RawMessage
+
+	if err := cli.HTTP(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return content, fmt.Errorf("get room state %s: %w", eventType, err)
+	}
+
+	if err := json.Unmarshal(response, &content); err != nil {
+		return content, fmt.Errorf("unmarshal %s content: %w", eventType, err)
+	}
+
+	return content, nil
+}
+
+// PutState sets the state event with the given type and state key of the given room ID or alias to the given content.
+// Returns the ID of the state event.
+func PutState(
+	ctx context.Context, cli matrix.Client, id ID, eventType, stateKey string, content interface{},
+) (string, error) {
+	if eventType == "" || content == nil {
+		panic("parameter empty")
+	}
+
+	path, err := roomPath(ctx, cli, id, "state", eventType, stateKey)
+	if err != nil {
+		return "", err
+	}
+
+	var response sendResponse
+
+	if err := cli.HTTP(ctx, http.MethodPut, path, content, &response); err != nil {
+		return "", fmt.Errorf("put room state %s: %w", eventType, err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return "", err
+	}
+
+	return response.ID, nil
+}
+
+// SetName sets the name of the given room ID or alias. Returns the ID of the state event.
+func SetName(ctx context.Context, cli matrix.Client, id ID, name string) (string, error) {
+	return PutState(ctx, cli, id, EventTypeName, "", NameContent{name})
+}
+
+// SetTopic sets the topic of the given room ID or alias. Returns the ID of the state event.
+func SetTopic(ctx context.Context, cli matrix.Client, id ID, topic string) (string, error) {
+	return PutState(ctx, cli, id, EventTypeTopic, "", TopicContent{topic})
+}
+
+// SetAvatar sets the avatar of the given room ID or alias. Returns the ID of the state event.
+func SetAvatar(ctx context.Context, cli matrix.Client, id ID, avatar AvatarContent) (string, error) {
+	return PutState(ctx, cli, id, EventTypeAvatar, "", avatar)
+}
+
+// SetPinnedEvents sets the pinned events of the given room ID or alias to the given event IDs. Returns the ID of the
+// state event.
+func SetPinnedEvents(ctx context.Context, cli matrix.Client, id ID, eventIDs ...string) (string, error) {
+	if eventIDs == nil {
+		eventIDs = []string{}
+	}
+
+	return PutState(ctx, cli, id, EventTypePinnedEvents, "", PinnedEventsContent{eventIDs})
+}
+
+// SetPowerLevels sets the power levels of the given room ID or alias. The content replaces the current power levels
+// completely, so it should be based on the current ones. Returns the ID of the state event.
+func SetPowerLevels(ctx context.Context, cli matrix.Client, id ID, levels PowerLevelsContent) (string, error) {
+	return PutState(ctx, cli, id, EventTypePowerLevels, "", levels)
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import "eqrx.net/matrix/event"
+
+// EventTypeTag in a event type field indicates that the room account data event contains tags of the room.
+const EventTypeTag = "m.tag"
+
+// Well known tags. Custom tags should use the u. prefix.
+const (
+	TagFavourite    = "m.favourite"
+	TagLowPriority  = "m.lowpriority"
+	TagServerNotice = "m.server_notice"
+)
+
+// TagEvent is the room account data event that contains the tags of a room.
+type TagEvent struct {
+	event.Metadata
+	Content TagContent `json:"content"`
+}
+
+// TagContent represents the content of a room tag event. Maps tag names to tag info.
+type TagContent struct {
+	Tags map[string]Tag `json:"tags"`
+}
+
+// Tag info. Order is a number between 0 and 1 that sorts rooms with the same tag.
+type Tag struct {
+	Order *float64 `json:"order,omitempty"`
+}
+
+// AsTagEvent converts the given opaque event to room tags. Returns an error if metadata indicates that the event is
+// not a tag event or if unmarshalling of the content failed.
+func AsTagEvent(evt event.Opaque) (TagEvent, error) {
+	content, err := event.As[TagContent](evt)
+
+	return TagEvent{evt.Metadata, content}, err
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"eqrx.net/matrix"
+	"eqrx.net/matrix/account"
+	"eqrx.net/matrix/event"
+	"eqrx.net/matrix/sync"
+)
+
+// Upgrade the given room ID or alias to the given room version with the client. The server creates a replacement
+// room and sends a tombstone into the old one. Returns the ID of the replacement room.
+func Upgrade(ctx context.Context, cli matrix.Client, id ID, newVersion string) (ID, error) {
+	if newVersion == "" {
+		panic("room version empty")
+	}
+
+	path, err := roomPath(ctx, cli, id, "upgrade")
+	if err != nil {
+		return "", err
+	}
+
+	request := struct {
+		NewVersion string `json:"new_version"`
+	}{newVersion}
+
+	var response struct {
+		matrix.Response
+		ReplacementRoom ID `json:"replacement_room"`
+	}
+
+	if err := cli.HTTP(ctx, http.MethodPost, path, request, &response); err != nil {
+		return "", fmt.Errorf("upgrade room: %w", err)
+	}
+
+	if err := response.AsError(); err != nil {
+		return "", err
+	}
+
+	return response.ReplacementRoom, nil
+}
+
+// PredecessorOf returns the room the given room ID or alias replaced according to its create event. Returns nil if the
+// room is no replacement.
+func PredecessorOf(ctx context.Context, cli matrix.Client, id ID) (*Predecessor, error) {
+	create, err := GetState[CreateContent](ctx, cli, id, EventTypeCreate, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return create.Predecessor, nil
+}
+
+// Tombstone returns the latest tombstone event of the given joined room of a sync response. Timeline events take
+// precedence over state events. Returns false if the room contains no tombstone with a replacement room.
+func Tombstone(room sync.JoinedRoom) (StateEvent[TombstoneContent], bool) {
+	for _, events := range [][]event.Opaque{room.Timeline.Events, room.State.Events} {
+		for i := len(events) - 1; i >= 0; i-- {
+			if events[i].Type != EventTypeTombstone {
+				continue
+			}
+
+			tombstone, err := AsStateEvent[TombstoneContent](events[i])
+			if err == nil && tombstone.Content.ReplacementRoom != "" {
+				return tombstone, true
+			}
+		}
+	}
+
+	return StateEvent[TombstoneContent]{}, false
+}
+
+// FollowOptions configures FollowTombstones.
+type FollowOptions struct {
+	// AccountDataTypes are the types of room account data copied from the old room to the replacement room.
+	AccountDataTypes []string
+	// Leave the old room after joining the replacement room.
+	Leave bool
+	// OnFollow is called after the replacement room was joined and the account data copied. May be nil.
+	OnFollow func(ctx context.Context, old, replacement ID) error
+}
+
+// FollowTombstones joins the replacement rooms of all joined rooms of the given sync rooms that contain a tombstone
+// with the client. The server of the sender of the tombstone is used to join through. Returns the IDs of the joined
+// replacement rooms. Stops at the first room that fails and returns the rooms followed until then.
+//
+// A tombstone stays in the state of the old room, so it is seen again by every initial sync unless the old room is
+// left. Tombstones whose replacement room the client is already joined to are skipped, so account data is only
+// copied and OnFollow only called once per replacement room.
+func FollowTombstones(ctx context.Context, cli matrix.Client, rooms sync.Rooms, opts FollowOptions) ([]ID, error) {
+	followed := make([]ID, 0)
+
+	var joined map[ID]struct{}
+
+	for id, room := range rooms.Joined {
+		tombstone, ok := Tombstone(room)
+		if !ok {
+			continue
+		}
+
+		if _, ok := rooms.Joined[string(tombstone.Content.ReplacementRoom)]; ok {
+			continue
+		}
+
+		if joined == nil {
+			ids, err := Joined(ctx, cli)
+			if err != nil {
+				return followed, err
+			}
+
+			joined = make(map[ID]struct{}, len(ids))
+			for _, joinedID := range ids {
+				joined[joinedID] = struct{}{}
+			}
+		}
+
+		if _, ok := joined[tombstone.Content.ReplacementRoom]; ok {
+			continue
+		}
+
+		replacement, err := follow(ctx, cli, ID(id), tombstone, opts)
+		if err != nil {
+			return followed, fmt.Errorf("follow tombstone of %s: %w", id, err)
+		}
+
+		joined[replacement] = struct{}{}
+		followed = append(followed, replacement)
+	}
+
+	return followed, nil
+}
+
+func follow(
+	ctx context.Context, cli matrix.Client, old ID, tombstone StateEvent[TombstoneContent], opts FollowOptions,
+) (ID, error) {
+	replacement, err := Join(ctx, cli, tombstone.Content.ReplacementRoom, "", ServerName(tombstone.Sender))
+	if err != nil {
+		return "", err
+	}
+
+	for _, eventType := range opts.AccountDataTypes {
+		content, err := account.GetRoom[json.RawMessage](ctx, cli, old.String(), eventType)
+
+		switch {
+		case matrix.IsErrorCode(err, "M_NOT_FOUND"):
+			continue
+		case err != nil:
+			return replacement, err
+		}
+
+		if err := account.PutRoom(ctx, cli, replacement.String(), eventType, content); err != nil {
+			return replacement, err
+		}
+	}
+
+	if opts.OnFollow != nil {
+		if err := opts.OnFollow(ctx, old, replacement); err != nil {
+			return replacement, err
+		}
+	}
+
+	if opts.Leave {
+		if err := Leave(ctx, cli, old, ""); err != nil {
+			return replacement, err
+		}
+	}
+
+	return replacement, nil
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+package sign
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownKey is returned by a KeyRing that does not know a requested key.
+var ErrUnknownKey = errors.New("unknown key")
+
+// KeyRing provides public keys of signing entities.
+type KeyRing interface {
+	// PublicKey returns the public key with the given ID of the given entity. Returns an error wrapping
+	// ErrUnknownKey if the key is not known.
+	PublicKey(ctx context.Context, entity, keyID string) (ed25519.PublicKey, error)
+}
+
+// StaticKeyRing is a KeyRing that holds its keys in memory. It is safe for concurrent use.
+type StaticKeyRing struct {
+	mtx  sync.RWMutex
+	keys map[string]map[string]ed25519.PublicKey
+}
+
+// NewStaticKeyRing creates an empty StaticKeyRing.
+func NewStaticKeyRing() *StaticKeyRing {
+	return &StaticKeyRing{keys: map[string]map[string]ed25519.PublicKey{}}
+}
+
+// Add the given public key with the given ID of the given entity to the ring.
+func (s *StaticKeyRing) Add(entity, keyID string, public ed25519.PublicKey) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.keys[entity] == nil {
+		s.keys[entity] = map[string]ed25519.PublicKey{}
+	}
+
+	s.keys[entity][keyID] = public
+}
+
+// PublicKey implements KeyRing.
+func (s *StaticKeyRing) PublicKey(_ context.Context, entity, keyID string) (ed25519.PublicKey, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	public, ok := s.keys[entity][keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrUnknownKey, entity, keyID)
+	}
+
+	return public, nil
+}
+
+// VerifyWith checks that the given object carries a valid ed25519 signature of each of the given entities made with a
+// key the given key ring knows. Signatures made with keys unknown to the ring are ignored.
+func VerifyWith(ctx context.Context, object interface{}, ring KeyRing, entities ...string) error {
+	fields, sigs, err := split(object)
+	if err != nil {
+		return err
+	}
+
+	for _, entity := range entities {
+		keyIDs := make([]string, 0, len(sigs[entity]))
+
+		for keyID := range sigs[entity] {
+			if strings.HasPrefix(keyID, AlgorithmEd25519+":") {
+				keyIDs = append(keyIDs, keyID)
+			}
+		}
+
+		sort.Strings(keyIDs)
+
+		if err := verifyEntity(ctx, fields, sigs, ring, entity, keyIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func verifyEntity(
+	ctx context.Context, fields map[string]json.RawMessage, sigs signatures, ring KeyRing, entity string,
+	keyIDs []string,
+) error {
+	for _, keyID := range keyIDs {
+		public, err := ring.PublicKey(ctx, entity, keyID)
+
+		switch {
+		case errors.Is(err, ErrUnknownKey):
+			continue
+		case err != nil:
+			return fmt.Errorf("get key %s of %s: %w", keyID, entity, err)
+		}
+
+		return verify(fields, sigs, entity, keyID, public)
+	}
+
+	return fmt.Errorf("%w: %s has no signature with a known key", ErrNoSignature, entity)
+}
@@ -0,0 +1,200 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+
+// Package sign signs and verifies JSON objects as defined by the matrix specification. The signature covers the
+// canonical JSON of the object without its signatures and unsigned fields and is stored in the signatures field,
+// keyed by the signing entity (a server name or user ID) and the key ID.
+package sign
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"eqrx.net/matrix/canonical"
+)
+
+// AlgorithmEd25519 is the prefix of IDs of ed25519 keys.
+const AlgorithmEd25519 = "ed25519"
+
+var (
+	// ErrNoSignature is returned if an object does not carry a signature to verify.
+	ErrNoSignature = errors.New("no signature")
+	// ErrInvalidSignature is returned if a signature does not match the object.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Key is an ed25519 signing key with its key ID like "ed25519:abc".
+type Key struct {
+	ID      string
+	Private ed25519.PrivateKey
+}
+
+// NewKey creates a signing key with the given version (the part of the key ID after the algorithm) from the given seed.
+func NewKey(version string, seed []byte) Key {
+	if version == "" || len(seed) != ed25519.SeedSize {
+		panic("invalid key parameters")
+	}
+
+	return Key{AlgorithmEd25519 + ":" + version, ed25519.NewKeyFromSeed(seed)}
+}
+
+// Public returns the public key of the key.
+func (k Key) Public() ed25519.PublicKey {
+	public, _ := k.Private.Public().(ed25519.PublicKey)
+
+	return public
+}
+
+// EncodeBase64 encodes the given bytes as unpadded base64 like matrix uses it.
+func EncodeBase64(data []byte) string {
+	return base64.RawStdEncoding.EncodeToString(data)
+}
+
+// DecodeBase64 decodes unpadded base64. Padded input is accepted as well.
+func DecodeBase64(encoded string) ([]byte, error) {
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+
+	return data, nil
+}
+
+type signatures map[string]map[string]string
+
+// split returns the fields of the given object and its signatures.
+func split(object interface{}) (map[string]json.RawMessage, signatures, error) {
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal object: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal object: %w", err)
+	}
+
+	sigs := signatures{}
+
+	if raw, ok := fields["signatures"]; ok {
+		if err := json.Unmarshal(raw, &sigs); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal signatures: %w", err)
+		}
+	}
+
+	return fields, sigs, nil
+}
+
+// signedBytes returns the canonical JSON of the given object fields without signatures and unsigned.
+func signedBytes(fields map[string]json.RawMessage) ([]byte, error) {
+	stripped := make(map[string]json.RawMessage, len(fields))
+
+	for key, value := range fields {
+		if key != "signatures" && key != "unsigned" {
+			stripped[key] = value
+		}
+	}
+
+	return canonical.Marshal(stripped)
+}
+
+// Signature computes the signature of the given object with the given key and returns it base64 encoded.
+func Signature(object interface{}, key Key) (string, error) {
+	fields, _, err := split(object)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := signedBytes(fields)
+	if err != nil {
+		return "", err
+	}
+
+	return EncodeBase64(ed25519.Sign(key.Private, signed)), nil
+}
+
+// Object signs the given object with the given key on behalf of the given entity. Returns the object with the
+// signature added to its signatures. Existing signatures are kept.
+func Object(object interface{}, entity string, key Key) (json.RawMessage, error) {
+	if entity == "" || !strings.HasPrefix(key.ID, AlgorithmEd25519+":") {
+		panic("invalid signing parameters")
+	}
+
+	fields, sigs, err := split(object)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := signedBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if sigs[entity] == nil {
+		sigs[entity] = map[string]string{}
+	}
+
+	sigs[entity][key.ID] = EncodeBase64(ed25519.Sign(key.Private, signed))
+
+	encodedSigs, err := json.Marshal(sigs)
+	if err != nil {
+		return nil, fmt.Errorf("marshal signatures: %w", err)
+	}
+
+	fields["signatures"] = encodedSigs
+
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("marshal object: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// Verify checks that the given object carries a valid signature of the given entity made with the key of the given
+// ID whose public key is given.
+func Verify(object interface{}, entity, keyID string, public ed25519.PublicKey) error {
+	fields, sigs, err := split(object)
+	if err != nil {
+		return err
+	}
+
+	return verify(fields, sigs, entity, keyID, public)
+}
+
+func verify(fields map[string]json.RawMessage, sigs signatures, entity, keyID string, public ed25519.PublicKey) error {
+	encodedSig, ok := sigs[entity][keyID]
+	if !ok {
+		return fmt.Errorf("%w: %s %s", ErrNoSignature, entity, keyID)
+	}
+
+	sig, err := DecodeBase64(encodedSig)
+	if err != nil {
+		return fmt.Errorf("signature of %s %s: %w", entity, keyID, err)
+	}
+
+	signed, err := signedBytes(fields)
+	if err != nil {
+		return err
+	}
+
+	if len(public) != ed25519.PublicKeySize || !ed25519.Verify(public, signed, sig) {
+		return fmt.Errorf("%w: %s %s", ErrInvalidSignature, entity, keyID)
+	}
+
+	return nil
+}
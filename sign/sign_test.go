@@ -0,0 +1,97 @@
+// Copyright (C) 2022 Alexander Sowitzki
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the
+// GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied
+// warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more
+// details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program.
+// If not, see <https://www.gnu.org/licenses/>.
+package sign_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"eqrx.net/matrix/event"
+	"eqrx.net/matrix/sign"
+)
+
+// testKey returns the signing key used by the examples in the appendix of the matrix specification.
+func testKey(t *testing.T) sign.Key {
+	t.Helper()
+
+	seed, err := sign.DecodeBase64("YJDBA9Xnr2sVqXD9Vj7XVUnmFZcZrlw8Md7kMW+3XA1")
+	if err != nil {
+		t.Fatalf("decode seed: %v", err)
+	}
+
+	return sign.NewKey("1", seed)
+}
+
+func TestSignature(t *testing.T) {
+	t.Parallel()
+
+	key := testKey(t)
+
+	for input, want := range map[string]string{
+		`{}`:                       "K8280/U9SSy9IVtjBuVeLr+HpOB4BQFWbg+UZaADMtTdGYI7Geitb76LTrr5QV/7Xg4ahLwYGYZzuHGZKM5ZAQ",
+		`{"one": 1, "two": "Two"}`: "KqmLSbO39/Bzb0QIYE82zqLwsA+PDzYIpIRA2sRQ4sL53+sN6/fpNSoqE7BP7vBZhG6kYdD13EIMJpvhJI+6Bw",
+	} {
+		got, err := sign.Signature(json.RawMessage(input), key)
+		if err != nil {
+			t.Errorf("sign %s: %v", input, err)
+
+			continue
+		}
+
+		if got != want {
+			t.Errorf("sign %s: got %s, want %s", input, got, want)
+		}
+	}
+}
+
+func TestEventSignature(t *testing.T) {
+	t.Parallel()
+
+	const input = `{"room_id":"!x:domain","sender":"@a:domain","origin":"domain","origin_server_ts":1000000,
+		"signatures":{},"hashes":{"sha256":"5jM4wQpv6lnBo7CLIghJuHdW+s2CMBJPUOGOC89ncos"},"type":"X","content":{},
+		"prev_events":[],"auth_events":[],"depth":3,"unsigned":{"age_ts":1000000}}`
+
+	var evt event.Opaque
+	if err := json.Unmarshal([]byte(input), &evt); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	redacted, err := event.Redact(evt, "1")
+	if err != nil {
+		t.Fatalf("redact: %v", err)
+	}
+
+	key := testKey(t)
+
+	signed, err := sign.Object(redacted, "domain", key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	var fields struct {
+		Signatures map[string]map[string]string `json:"signatures"`
+	}
+
+	if err := json.Unmarshal(signed, &fields); err != nil {
+		t.Fatalf("unmarshal signed event: %v", err)
+	}
+
+	want := "KxwGjPSDEtvnFgU00fwFz+l6d2pJM6XBIaMEn81SXPTRl16AqLAYqfIReFGZlHi5KLjAWbOoMszkwsQma+lYAg"
+	if got := fields.Signatures["domain"][key.ID]; got != want {
+		t.Errorf("got signature %s, want %s", got, want)
+	}
+
+	if err := sign.Verify(signed, "domain", key.ID, key.Public()); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+}
@@ -14,20 +14,55 @@
 package sync
 
 import (
+	"time"
+
 	"eqrx.net/matrix"
 	"eqrx.net/matrix/event"
 )
 
-// Response of a sync request.
+// Response of a sync request. Received is the local time the response was received. It is also set as receive time
+// of all contained events. Optional maps and lists that are empty are left out when marshalling.
 type Response struct {
 	matrix.Response
 	AccountData            EventContainer `json:"account_data"`
 	DeviceLists            DeviceLists    `json:"device_lists"`
-	DeviceOneTimeKeysCount map[string]int `json:"device_one_time_keys_count"`
+	DeviceOneTimeKeysCount map[string]int `json:"device_one_time_keys_count,omitempty"`
 	NextBatch              string         `json:"next_batch"`
 	Presence               EventContainer `json:"presence"`
 	Rooms                  Rooms          `json:"rooms"`
 	ToDevice               EventContainer `json:"to_device"`
+	Received               time.Time      `json:"-"`
+}
+
+// stamp sets the given time as receive time of the response and all events in it.
+func (r *Response) stamp(received time.Time) {
+	r.Received = received
+
+	stampEvents(received, r.AccountData.Events, r.Presence.Events, r.ToDevice.Events)
+
+	for _, room := range r.Rooms.Invited {
+		stampEvents(received, room.State.Events)
+	}
+
+	for _, room := range r.Rooms.Joined {
+		stampEvents(received, room.AccountData.Events, room.Ephemeral.Events, room.State.Events, room.Timeline.Events)
+	}
+
+	for _, room := range r.Rooms.Knocked {
+		stampEvents(received, room.KnockState.Events)
+	}
+
+	for _, room := range r.Rooms.Left {
+		stampEvents(received, room.AccountData.Events, room.State.Events, room.Timeline.Events)
+	}
+}
+
+func stampEvents(received time.Time, lists ...[]event.Opaque) {
+	for _, events := range lists {
+		for i := range events {
+			events[i].Received = received
+		}
+	}
 }
 
 // EventContainer represents map that just contains an event field.
@@ -39,16 +74,16 @@ type EventContainer struct {
 
 // DeviceLists contains information about device changes.
 type DeviceLists struct {
-	Changed []string `json:"changed"`
-	Left    []string `json:"left"`
+	Changed []string `json:"changed,omitempty"`
+	Left    []string `json:"left,omitempty"`
 }
 
 // Rooms contains information about rooms the client has interacted with, grouped by state.
 type Rooms struct {
-	Invited map[string]InvitedRoom `json:"invite"`
-	Joined  map[string]JoinedRoom  `json:"join"`
-	Knocked map[string]KnockedRoom `json:"knock"`
-	Left    map[string]LeftRoom    `json:"leave"`
+	Invited map[string]InvitedRoom `json:"invite,omitempty"`
+	Joined  map[string]JoinedRoom  `json:"join,omitempty"`
+	Knocked map[string]KnockedRoom `json:"knock,omitempty"`
+	Left    map[string]LeftRoom    `json:"leave,omitempty"`
 }
 
 // InvitedRoom is a room the client was invited to.
@@ -56,25 +91,27 @@ type InvitedRoom struct {
 	State EventContainer `json:"invite_state"`
 }
 
-// JoinedRoom is a room the client has joined.
+// JoinedRoom is a room the client has joined. UnreadThreadNotifications maps thread root event IDs to the
+// notification counts of that thread and is only set if the sync filter requested it.
 type JoinedRoom struct {
-	AccountData               EventContainer            `json:"account_data"`
-	Ephemeral                 EventContainer            `json:"ephemeral"`
-	State                     EventContainer            `json:"state"`
-	Summary                   RoomSummary               `json:"summary"`
-	Timeline                  Timeline                  `json:"timeline"`
-	UnreadNotificationsCounts UnreadNotificationsCounts `json:"unread_notifications"`
+	AccountData               EventContainer                       `json:"account_data"`
+	Ephemeral                 EventContainer                       `json:"ephemeral"`
+	State                     EventContainer                       `json:"state"`
+	Summary                   RoomSummary                          `json:"summary"`
+	Timeline                  Timeline                             `json:"timeline"`
+	UnreadNotificationsCounts UnreadNotificationsCounts            `json:"unread_notifications"`
+	UnreadThreadNotifications map[string]UnreadNotificationsCounts `json:"unread_thread_notifications,omitempty"`
 }
 
-// UnreadNotificationsCounts contains notication counts of a joined room.
+// UnreadNotificationsCounts contains notication counts of a joined room or a thread within it.
 type UnreadNotificationsCounts struct {
 	Highlighted int `json:"highlight_count"`
-	Total       int `json:"total"`
+	Total       int `json:"notification_count"`
 }
 
 // RoomSummary for joined rooms.
 type RoomSummary struct {
-	Heros              []string `json:"m.heroes"`
+	Heros              []string `json:"m.heroes,omitempty"`
 	InvitedMemberCount int      `json:"m.invited_member_count"`
 	JoinedMemeberCount int      `json:"m.joined_member_count"`
 }
@@ -83,7 +120,7 @@ type RoomSummary struct {
 type Timeline struct {
 	Events        []event.Opaque `json:"events"`
 	Limited       bool           `json:"limited"`
-	PreviousBatch string         `json:"prev_batch"`
+	PreviousBatch string         `json:"prev_batch,omitempty"`
 }
 
 // KnockedRoom is a room the client has knocked on.
@@ -94,6 +131,6 @@ type KnockedRoom struct {
 // LeftRoom is a room the client has left.
 type LeftRoom struct {
 	AccountData EventContainer `json:"account_data"`
-	State       EventContainer `json:"ephemeral"`
+	State       EventContainer `json:"state"`
 	Timeline    Timeline       `json:"timeline"`
 }
@@ -50,5 +50,7 @@ func Sync(ctx context.Context, cli matrix.Client, since, filter string, timeoutM
 		return response, fmt.Errorf("sync: %w", err)
 	}
 
+	response.stamp(time.Now())
+
 	return response, nil
 }